@@ -1,6 +1,8 @@
 package app
 
 import (
+	"context"
+
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 )
@@ -20,11 +22,14 @@ func DeactivateCmd() tea.Msg {
 type AppModel struct {
 	menu          tea.Model
 	selectedModel tea.Model
+	size          tea.WindowSizeMsg // Passed on to newly selected models, which would otherwise start out sizeless
 }
 
-func NewAppModel(r *lipgloss.Renderer) AppModel {
+// player is a stable id for whoever's connected (their public key), or empty if they didn't offer one.
+// ctx lives as long as their connection, and ends any multiplayer match they're in when it's canceled
+func NewAppModel(ctx context.Context, r *lipgloss.Renderer, player string) AppModel {
 	return AppModel{
-		menu: NewMenuModel(),
+		menu: NewMenuModel(ctx, player),
 	}
 }
 
@@ -39,13 +44,17 @@ func (a AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		// Could maybe deal with propogating window sizes to components through a pointer?
 		appStyle.Height(msg.Height)
 		appStyle.Width(msg.Width)
+		a.size = msg
 		a.menu, _ = a.menu.Update(msg)
 	case MenuSelectMsg:
-		a.selectedModel = msg.model
+		a.selectedModel, _ = msg.model.Update(a.size)
 		return a, a.selectedModel.Init()
 	case DeactivateMsg:
 		if a.selectedModel != nil {
 			a.selectedModel = nil // drop *tea.Model contents
+			// Saved games and the like might have changed while the menu was hidden
+			a.menu, cmd = a.menu.Update(menuRefreshMsg{})
+			return a, cmd
 		} else {
 			// Else we are completely closing the app
 			return a, tea.Quit
@@ -0,0 +1,168 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"tetrissh/bot"
+	"tetrissh/tetris"
+	"time"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// How well a CPU opponent plays
+type Difficulty struct {
+	Name    string
+	PPS     float64 // Pieces per second it's capped at
+	Weights bot.Weights
+}
+
+var (
+	DifficultyEasy = Difficulty{
+		Name: "Easy",
+		PPS:  0.75,
+		// Only minds holes and how high it's stacking
+		Weights: bot.Weights{LandingHeight: -1, Holes: -2},
+	}
+	DifficultyMedium = Difficulty{Name: "Medium", PPS: 1.5, Weights: bot.DellacherieWeights()}
+	DifficultyHard   = Difficulty{Name: "Hard", PPS: 3, Weights: bot.DefaultWeights()}
+)
+
+var Difficulties = []Difficulty{DifficultyEasy, DifficultyMedium, DifficultyHard}
+
+// A bot playing its own game in the background. It keeps the same session a person's game would,
+// which is how the player reads it. Safe to read from other goroutines
+type CPUOpponent struct {
+	*MultiplayerSession
+	difficulty Difficulty
+	runner     *GameRunner
+	bot        bot.Bot
+	perPiece   int // Frames between pieces, which is what caps its speed
+
+	// Only touched on the runner's goroutine
+	nextPiece int // Frame the next piece can be placed on
+
+	mx   sync.Mutex
+	plan *cpuPlan // The next placement, once it's been worked out
+}
+
+// A placement, and the piece it was worked out for
+type cpuPlan struct {
+	bot.Placement
+	key cpuPlanKey
+}
+
+// Where the active piece was when a plan was made. Plans for anything else are stale
+type cpuPlanKey struct {
+	placed int
+	piece  string
+	rot    tetris.Rotation
+	x, y   int
+}
+
+func planKey(g tetris.Game) (cpuPlanKey, bool) {
+	a, ok := g.Active()
+	return cpuPlanKey{placed: g.Placed(), piece: a.Piece.Name(), rot: a.Rotation, x: a.X, y: a.Y}, ok
+}
+
+// Start a CPU playing against target at start, until ctx is canceled or it tops out
+func NewCPUOpponent(ctx context.Context, d Difficulty, seed uint64, start time.Time, target *MultiplayerSession) *CPUOpponent {
+	// Its own, so it can leave the match if its game crashes
+	ctx, cancel := context.WithCancel(ctx)
+	g := tetris.NewGame(boardHeight, boardWidth, seed, tetris.DefaultRules())
+	perPiece := int(tetris.FramesPerSecond / d.PPS)
+	c := &CPUOpponent{
+		MultiplayerSession: NewMultiplayerSession(ctx),
+		difficulty:         d,
+		bot:                bot.New(d.Weights),
+		perPiece:           perPiece,
+		nextPiece:          perPiece,
+	}
+	c.SetBoard(g.Board())
+	c.SetStats(gameStats(g, 0))
+
+	exchange := exchangeGarbage(c.MultiplayerSession, target)
+	c.runner = NewGameRunner(ctx, g, start, func(g *tetris.Game, events []tetris.Event) {
+		exchange(g, append(events, c.place(g)...))
+	}, cancel)
+
+	go c.think()
+	return c
+}
+
+// Stop playing where it is
+func (c *CPUOpponent) stop() {
+	c.runner.Stop()
+}
+
+// Work out where each piece goes on snapshots of the game, so searching never holds up its frames
+func (c *CPUOpponent) think() {
+	ticker := time.NewTicker(frameDuration)
+	defer ticker.Stop()
+
+	var planned cpuPlanKey
+	for {
+		select {
+		case <-c.runner.done():
+			return
+		case <-ticker.C:
+		}
+
+		g, _ := c.runner.Snapshot()
+		key, ok := planKey(g)
+		if !ok || key == planned {
+			continue
+		}
+		p, ok := c.bot.Best(g)
+		if !ok {
+			continue
+		}
+
+		planned = key
+		c.mx.Lock()
+		c.plan = &cpuPlan{Placement: p, key: key}
+		c.mx.Unlock()
+	}
+}
+
+// Place the planned piece if it's time to and the plan is still for the piece that's falling.
+// Runs on the runner's goroutine, and returns what happened
+func (c *CPUOpponent) place(g *tetris.Game) []tetris.Event {
+	c.mx.Lock()
+	plan := c.plan
+	c.mx.Unlock()
+
+	if plan == nil || g.Frames() < c.nextPiece {
+		return nil
+	}
+	// Gravity might have moved it since, then it's planned again
+	if key, ok := planKey(*g); !ok || key != plan.key {
+		return nil
+	}
+
+	c.mx.Lock()
+	c.plan = nil
+	c.mx.Unlock()
+	c.nextPiece = g.Frames() + c.perPiece
+	return g.Step(0, plan.Actions)
+}
+
+/*** DIFFICULTY LIST ***/
+
+// Pick a difficulty to play VS against the CPU
+func NewDifficultyList(ctx context.Context) ItemList {
+	var items []list.Item
+	for _, d := range Difficulties {
+		items = append(items, MenuItem{
+			title: d.Name,
+			desc:  fmt.Sprintf("Up to %v pieces a second", d.PPS),
+			newModel: func() tea.Model {
+				return NewCPUMultiplayer(ctx, d)
+			},
+		})
+	}
+
+	return NewItemList("VS CPU", items)
+}
@@ -1,6 +1,8 @@
 package app
 
 import (
+	"context"
+	"fmt"
 	"tetrissh/tetris"
 	"time"
 
@@ -8,55 +10,176 @@ import (
 	"github.com/charmbracelet/lipgloss"
 )
 
+// How many upcoming pieces the next panel shows
+const previewLen = 5
+
+// Every game is played on a board this size
+const (
+	boardHeight = 20
+	boardWidth  = 10
+)
+
 // Core bubbletea model that wraps the tetris game as thinly as possible.
+// Live games are played by a GameRunner and the model only shows its snapshots,
+// replays are stepped right here
 type GameModel struct {
 	*tetris.Game
+	runner    *GameRunner // Plays the game when it's live, nil for replays
+	status    string      // Last thing worth telling the player about, like a T-spin or a level up
+	lastFrame time.Time   // Wall clock time the engine has been stepped up to
 }
 
-func NewGameModel() GameModel {
-	t := tetris.NewGame(20, 10, tetris.RandomPiece())
+func NewGameModel(seed uint64) GameModel {
+	t := tetris.NewGame(boardHeight, boardWidth, seed, tetris.DefaultRules())
 
 	return GameModel{Game: &t}
 }
 
-type FallMsg struct{}
+// Play g on a GameRunner right away, stopping when ctx is canceled
+func runGameModel(ctx context.Context, g tetris.Game) GameModel {
+	return GameModel{Game: &g, runner: NewGameRunner(ctx, g, time.Now(), nil, nil)}
+}
 
-func FallTickCmd() tea.Cmd {
-	return tea.Tick(time.Second, func(t time.Time) tea.Msg { return FallMsg{} })
+// Stop the runner playing the game, if there is one
+func (m GameModel) stop() {
+	if m.runner != nil {
+		m.runner.Stop()
+	}
 }
 
-func (m GameModel) Init() tea.Cmd { return FallTickCmd() }
+const frameDuration = time.Second / tetris.FramesPerSecond
+
+// Feeds wall clock time to the engine. All the game's timing happens in tetris.Game.Step
+type FrameMsg struct {
+	t time.Time
+}
+
+func FrameTickCmd() tea.Cmd {
+	return tea.Tick(frameDuration, func(t time.Time) tea.Msg { return FrameMsg{t: t} })
+}
+
+func (m GameModel) Init() tea.Cmd { return FrameTickCmd() }
+
+// How many whole frames have passed since the engine was last stepped.
+// Ticks don't arrive exactly on time, so leftover time carries over to the next one
+func (m *GameModel) elapsedFrames(t time.Time) int {
+	if m.lastFrame.IsZero() {
+		m.lastFrame = t
+		return 1
+	}
+
+	frames := int(t.Sub(m.lastFrame) / frameDuration)
+	m.lastFrame = m.lastFrame.Add(time.Duration(frames) * frameDuration)
+	return frames
+}
 
 func (m GameModel) Update(msg tea.Msg) (GameModel, tea.Cmd) {
 	var cmd tea.Cmd
+	var events []tetris.Event
 
 	switch msg := msg.(type) {
-	case FallMsg:
-		m.Fall()
+	case FrameMsg:
+		if m.runner != nil {
+			var g tetris.Game
+			g, events = m.runner.Snapshot()
+			m.Game = &g
+			if err := m.runner.Err(); err != nil {
+				m.status = fmt.Sprintf("Something went wrong, %v", err)
+				break
+			}
+		} else {
+			events = m.Step(m.elapsedFrames(msg.t), nil)
+		}
 		if !m.GameOver {
-			cmd = FallTickCmd()
+			cmd = FrameTickCmd()
 		}
 	case tea.KeyMsg:
-		switch msg.String() {
-		case "h", "left":
-			m.Act(tetris.ActionLeft)
-		case "l", "right":
-			m.Act(tetris.ActionRight)
-		case "j", "down":
-			m.Act(tetris.ActionDown)
-		case "k", "r", "up":
-			m.Act(tetris.ActionRotate)
-		case " ":
-			m.Act(tetris.ActionDrop)
+		if msg.String() == "f" {
+			m.exportFumen()
+		} else if a, ok := keyAction(msg); ok {
+			if m.runner != nil {
+				m.runner.Act(a)
+			} else {
+				events = m.Step(0, []tetris.Action{a})
+			}
 		}
 	}
 
+	m.handleEvents(events)
 	return m, cmd
 }
 
+func keyAction(msg tea.KeyMsg) (tetris.Action, bool) {
+	switch msg.String() {
+	case "h", "left":
+		return tetris.ActionLeft, true
+	case "l", "right":
+		return tetris.ActionRight, true
+	case "j", "down":
+		return tetris.ActionDown, true
+	case "k", "r", "x", "up":
+		return tetris.ActionRotate, true
+	case "z":
+		return tetris.ActionRotateCCW, true
+	case " ":
+		return tetris.ActionDrop, true
+	case "c":
+		return tetris.ActionHold, true
+	}
+	return 0, false
+}
+
+// React to what happened in the engine since the last update
+func (m *GameModel) handleEvents(events []tetris.Event) {
+	for _, e := range events {
+		switch e := e.(type) {
+		case tetris.PieceLocked:
+			// Spins that don't clear anything don't get a LinesCleared
+			if e.Clear.Spin != tetris.SpinNone && e.Clear.Lines() == 0 {
+				m.status = e.Clear.Name()
+			}
+		case tetris.LinesCleared:
+			m.status = e.Clear.Name()
+		case tetris.LevelUp:
+			m.status = fmt.Sprintf("Level %v!", e.Level)
+		case tetris.BoardReset:
+			m.status = "Board cleared"
+		}
+	}
+}
+
+// Put the board in the status line as a fumen, for copying out of the terminal
+func (m *GameModel) exportFumen() {
+	fumen, err := m.Fumen()
+	if err != nil {
+		m.status = fmt.Sprintf("Couldn't make a fumen: %v", err)
+		return
+	}
+	m.status = fumen
+}
+
 func (m GameModel) View() string {
-	board := BoardView(m)
-	score := ScoreView(m)
+	stats := lipgloss.JoinHorizontal(lipgloss.Top, ScoreView(m), LevelView(m))
 
-	return lipgloss.JoinVertical(lipgloss.Center, score, board)
+	return lipgloss.JoinVertical(lipgloss.Center, stats, m.PlayfieldView(), m.status)
+}
+
+func LevelView(m GameModel) string {
+	return scoreStyle.Render(fmt.Sprintf("Lv %v | %v lines", m.Level(), m.Lines()))
+}
+
+// The board with the hold and next panels on either side
+func (m GameModel) PlayfieldView() string {
+	return lipgloss.JoinHorizontal(lipgloss.Top, HoldView(m), BoardView(m), NextView(m))
+}
+
+func NextView(m GameModel) string {
+	return PiecePanel("Next", m.Pieces(), m.Next(previewLen)...)
+}
+
+func HoldView(m GameModel) string {
+	if p, ok := m.Hold(); ok {
+		return PiecePanel("Hold", m.Pieces(), p)
+	}
+	return PiecePanel("Hold", m.Pieces())
 }
@@ -11,54 +11,74 @@ import (
 var (
 	emptyStyle = lipgloss.NewStyle().Background(lipgloss.Color("233"))
 	blockStyle = lipgloss.NewStyle().Background(lipgloss.Color("240"))
+	ghostStyle = emptyStyle.Copy().Faint(true)
 	scoreStyle = lipgloss.NewStyle().
 			Width(18).
 			Border(lipgloss.NormalBorder(), true).
 			AlignHorizontal(lipgloss.Center)
+	// Side panels (hold, next) are at least 4 blocks wide, enough for an I piece
+	panelStyle = lipgloss.NewStyle().
+			Width(minPanelBlocks*2).
+			Border(lipgloss.NormalBorder(), true).
+			AlignHorizontal(lipgloss.Center)
 )
 
+const minPanelBlocks = 4
+
 type GameInfo interface {
 	Board() [][]int
+	// Where the active piece would land, same shape as Board. Can be nil
+	Ghost() [][]int
 	Score() int
 	// TODO: GameState?
 }
 
+// Terminal colors for the named tetris colors
+var colorCodes = map[tetris.Color]string{
+	tetris.ColorEmpty:  "240",
+	tetris.ColorRed:    "001",
+	tetris.ColorBlue:   "004",
+	tetris.ColorGreen:  "002",
+	tetris.ColorOrange: "202",
+	tetris.ColorPurple: "129",
+	tetris.ColorYellow: "011",
+	tetris.ColorCyan:   "014",
+	tetris.ColorGray:   "250",
+}
+
+// Anything we don't know how to draw comes out in this
+const unknownColor = "250"
+
 func toColor(ci int) lipgloss.Color {
 	c := tetris.Color(ci)
-	var code string
-
-	switch c {
-	case tetris.ColorEmpty:
-		code = "240"
-	case tetris.ColorRed:
-		code = "001"
-	case tetris.ColorBlue:
-		code = "004"
-	case tetris.ColorGreen:
-		code = "002"
-	case tetris.ColorOrange:
-		code = "202"
-	case tetris.ColorPurple:
-		code = "129"
-	case tetris.ColorYellow:
-		code = "011"
-	default:
-		// TODO: Only panic in development mode
-		panic("Trying to convert an int to a color but there were no matching colors")
+
+	if n, ok := c.ANSI(); ok {
+		return lipgloss.Color(fmt.Sprint(n))
 	}
-	return lipgloss.Color(code)
+	if _, _, _, ok := c.RGB(); ok {
+		return lipgloss.Color(c.String())
+	}
+	if code, ok := colorCodes[c]; ok {
+		return lipgloss.Color(code)
+	}
+	return lipgloss.Color(unknownColor)
 }
 
 func BoardView(g GameInfo) string {
-	var sb strings.Builder
+	return renderCells(g.Board(), g.Ghost())
+}
 
-	b := g.Board()
+// Draw a grid of colors. ghost is optional, if it's not nil it has to be the same shape as b
+func renderCells(b, ghost [][]int) string {
+	var sb strings.Builder
 
 	for y := range b {
 		for x := range b[y] {
 			val := b[y][x]
 			color := lipgloss.Color(toColor(val))
-			if val == 0 {
+			if val == 0 && ghost != nil && ghost[y][x] != 0 {
+				sb.WriteString(ghostStyle.Foreground(toColor(ghost[y][x])).Render("▒▒"))
+			} else if val == 0 {
 				sb.WriteString(emptyStyle.Foreground(color).Render("░░"))
 			} else {
 				sb.WriteString(blockStyle.Foreground(color).Render("🮑🮒"))
@@ -76,3 +96,39 @@ func BoardView(g GameInfo) string {
 func ScoreView(g GameInfo) string {
 	return scoreStyle.Render(fmt.Sprintf("Score: %v", g.Score()))
 }
+
+var garbageStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("001"))
+
+// Column as tall as the board, filled from the bottom with one block per line of incoming garbage
+func GarbageMeter(lines, height int) string {
+	var sb strings.Builder
+	for y := 0; y < height; y++ {
+		if height-y <= lines {
+			sb.WriteString(garbageStyle.Render("██"))
+		} else {
+			sb.WriteString(emptyStyle.Render("  "))
+		}
+		if y < height-1 {
+			sb.WriteRune('\n')
+		}
+	}
+	return sb.String()
+}
+
+// A bordered box with a title and some pieces stacked in it, for hold and next previews.
+// It's wide enough for the widest piece in set, so it doesn't change size as pieces come and go
+func PiecePanel(title string, set []tetris.Piece, pieces ...tetris.Piece) string {
+	views := []string{title}
+	for _, p := range pieces {
+		views = append(views, renderCells(p.Preview(), nil))
+	}
+
+	blocks := minPanelBlocks
+	for _, p := range set {
+		if preview := p.Preview(); len(preview) > 0 {
+			blocks = max(blocks, len(preview[0]))
+		}
+	}
+
+	return panelStyle.Copy().Width(blocks * 2).Render(lipgloss.JoinVertical(lipgloss.Center, views...))
+}
@@ -0,0 +1,48 @@
+package app
+
+import (
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// A titled list of MenuItems that opens whichever one is picked, for things like the replay and puzzle lists
+type ItemList struct {
+	list list.Model
+}
+
+func NewItemList(title string, items []list.Item) ItemList {
+	l := list.New(items, list.NewDefaultDelegate(), 0, 0)
+	l.Title = title
+
+	return ItemList{list: l}
+}
+
+func (l ItemList) Init() tea.Cmd {
+	return nil
+}
+
+func (l ItemList) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		l.list.SetSize(msg.Width, msg.Height)
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "enter":
+			if selected, ok := l.list.SelectedItem().(MenuItem); ok {
+				return l, selected.SelectCmd()
+			}
+			return l, nil
+		case "q", "ctrl+c":
+			return l, DeactivateCmd
+		}
+	}
+
+	l.list, cmd = l.list.Update(msg)
+	return l, cmd
+}
+
+func (l ItemList) View() string {
+	return l.list.View()
+}
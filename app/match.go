@@ -0,0 +1,239 @@
+package app
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"sync"
+	"tetrissh/tetris"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// What a player did over a match, shared with the opponent so both sides can
+// work out who won and show it on the results screen
+type MatchStats struct {
+	Score  int
+	Lines  int
+	Pieces int
+	Sent   int // Lines of garbage sent
+	Frames int
+	// The game's over. Frames is when it happened
+	ToppedOut bool
+}
+
+func gameStats(g tetris.Game, sent int) MatchStats {
+	return MatchStats{
+		Score:     g.Score(),
+		Lines:     g.Lines(),
+		Pieces:    g.Placed(),
+		Sent:      sent,
+		Frames:    g.Frames(),
+		ToppedOut: g.GameOver,
+	}
+}
+
+type matchResult int
+
+const (
+	resultWin matchResult = iota
+	resultLoss
+	resultDraw
+)
+
+// Work out how the match ended, if it has. Whoever tops out first loses, and topping out on the
+// same frame is a draw. Both sides come to the same answer, since a player who topped out waits
+// for the other to either top out too or play past that frame.
+// An opponent leaving before it's decided forfeits
+func resolveMatch(ours, theirs MatchStats, opponentLeft bool) (result matchResult, forfeit, ok bool) {
+	switch {
+	case ours.ToppedOut && theirs.ToppedOut:
+		if ours.Frames == theirs.Frames {
+			return resultDraw, false, true
+		} else if ours.Frames < theirs.Frames {
+			return resultLoss, false, true
+		}
+		return resultWin, false, true
+	case ours.ToppedOut && theirs.Frames > ours.Frames:
+		return resultLoss, false, true
+	case theirs.ToppedOut && ours.Frames > theirs.Frames:
+		return resultWin, false, true
+	case opponentLeft:
+		return resultWin, true, true
+	}
+	return 0, false, false
+}
+
+func (r matchResult) title(forfeit bool) string {
+	switch {
+	case forfeit:
+		return "You win! Your opponent left"
+	case r == resultWin:
+		return "You win!"
+	case r == resultLoss:
+		return "You lose"
+	default:
+		return "Draw"
+	}
+}
+
+/*** RESULTS SCREEN ***/
+
+var resultOptions = []string{"Rematch", "Back to menu"}
+
+var selectedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("129")).Bold(true)
+
+// Keys for the results screen
+func (m *MultiplayerGame) updateResults(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "up", "k":
+		m.selected = max(m.selected-1, 0)
+	case "down", "j":
+		m.selected = min(m.selected+1, len(resultOptions)-1)
+	case "r":
+		return m.rematch()
+	case "enter":
+		if m.selected == 0 {
+			return m.rematch()
+		}
+		m.close()
+		return DeactivateCmd
+	}
+	return nil
+}
+
+func (m *MultiplayerGame) renderResults() string {
+	ours, theirs := m.ours, m.theirs
+
+	rows := [][3]string{
+		{"", "You", "Them"},
+		{"Score", fmt.Sprint(ours.Score), fmt.Sprint(theirs.Score)},
+		{"Lines", fmt.Sprint(ours.Lines), fmt.Sprint(theirs.Lines)},
+		{"Pieces", fmt.Sprint(ours.Pieces), fmt.Sprint(theirs.Pieces)},
+		{"Garbage sent", fmt.Sprint(ours.Sent), fmt.Sprint(theirs.Sent)},
+		{"Time", formatFrames(ours.Frames), formatFrames(theirs.Frames)},
+	}
+	var table strings.Builder
+	for _, r := range rows {
+		table.WriteString(fmt.Sprintf("%-14v%12v%12v\n", r[0], r[1], r[2]))
+	}
+
+	var options []string
+	for i, o := range resultOptions {
+		if i == m.selected {
+			options = append(options, selectedStyle.Render("> "+o))
+		} else {
+			options = append(options, "  "+o)
+		}
+	}
+
+	return lipgloss.JoinVertical(lipgloss.Left,
+		m.result.title(m.forfeit),
+		"",
+		table.String(),
+		lipgloss.JoinVertical(lipgloss.Left, options...),
+	)
+}
+
+// Start another match against the same opponent. People have to ask for the rematch too,
+// and if they've already left it's back to matchmaking
+func (m *MultiplayerGame) rematch() tea.Cmd {
+	var next *MultiplayerGame
+	op, human := m.opponent.(*MultiplayerSession)
+
+	switch {
+	case m.cpu != nil:
+		next = NewCPUMultiplayer(m.ctx, *m.cpu)
+	case !human || m.room == nil:
+		next = NewMultiplayer(m.ctx)
+	default:
+		next = newMultiplayerGame(m.ctx)
+		next.started = time.Now()
+
+		if opC, ok := m.room.ask(next.session, op); ok {
+			// Wait for the match like it's coming from matchmaking
+			next.opC = opC
+			next.room = m.room
+			next.rematchOf = op
+			// Passed on, so closing this game doesn't leave it
+			m.room = nil
+		} else {
+			next.req = Matchmaking.Request(next.session)
+			next.opC = next.req.opC
+		}
+	}
+
+	m.close()
+	return func() tea.Msg { return MenuSelectMsg{model: next} }
+}
+
+// Where the two players of a match meet up to play again. It isn't tied to either session,
+// since whoever asks first leaves theirs behind for their next game's. Safe to use from either player
+type rematchRoom struct {
+	mx    sync.Mutex
+	offer *rematchOffer // Whoever asked first, waiting on the other
+	over  bool          // The rematch started, or someone left
+}
+
+type rematchOffer struct {
+	session *MultiplayerSession // Their next game's
+	seed    uint64
+	opC     chan match
+}
+
+// Ask for a rematch to be played in next, against whoever was playing opponent. The match comes
+// through the channel once both players have asked, or it's closed without one if they leave
+// instead. Not ok if they've already left
+func (r *rematchRoom) ask(next, opponent *MultiplayerSession) (<-chan match, bool) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	if r.over {
+		return nil, false
+	}
+
+	if o := r.offer; o != nil {
+		// They asked first, so it's on
+		r.offer, r.over = nil, true
+		if isDone(o.session) {
+			close(o.opC)
+			return nil, false
+		}
+
+		ours, theirs := pairUp(next, o.session, o.seed)
+		o.opC <- theirs
+		close(o.opC)
+
+		opC := make(chan match, 1)
+		opC <- ours
+		close(opC)
+		return opC, true
+	}
+
+	// Their session only gets canceled without an offer if they're gone for good
+	if isDone(opponent) {
+		r.over = true
+		return nil, false
+	}
+	r.offer = &rematchOffer{session: next, seed: rand.Uint64(), opC: make(chan match, 1)}
+	return r.offer.opC, true
+}
+
+// Not playing again. Whoever's waiting on the rematch has their offer closed.
+// Reports whether the rematch was still open, and if it wasn't the offer's already been answered
+func (r *rematchRoom) leave() bool {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	if r.over {
+		return false
+	}
+	r.over = true
+	if r.offer != nil {
+		close(r.offer.opC)
+		r.offer = nil
+	}
+	return true
+}
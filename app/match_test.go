@@ -0,0 +1,149 @@
+package app
+
+import (
+	"context"
+	"testing"
+)
+
+func TestResolveMatch(t *testing.T) {
+	playing := func(frames int) MatchStats { return MatchStats{Frames: frames} }
+	toppedOut := func(frames int) MatchStats { return MatchStats{Frames: frames, ToppedOut: true} }
+	opposite := map[matchResult]matchResult{resultWin: resultLoss, resultLoss: resultWin, resultDraw: resultDraw}
+
+	cases := []struct {
+		name    string
+		ours    MatchStats
+		theirs  MatchStats
+		left    bool
+		result  matchResult
+		forfeit bool
+		over    bool
+	}{
+		{"Both playing", playing(100), playing(100), false, 0, false, false},
+		{"We topped out first", toppedOut(100), playing(101), false, resultLoss, false, true},
+		{"They topped out first", playing(101), toppedOut(100), false, resultWin, false, true},
+		// They might still top out on the same frame
+		{"Waiting on them to catch up", toppedOut(100), playing(100), false, 0, false, false},
+		{"Waiting on us to catch up", playing(99), toppedOut(100), false, 0, false, false},
+		{"Same frame", toppedOut(100), toppedOut(100), false, resultDraw, false, true},
+		{"Both out, us first", toppedOut(90), toppedOut(100), false, resultLoss, false, true},
+		{"Both out, them first", toppedOut(100), toppedOut(90), false, resultWin, false, true},
+		{"They left", playing(100), playing(50), true, resultWin, true, true},
+		{"They left before catching up", toppedOut(100), playing(50), true, resultWin, true, true},
+		{"They left after losing", toppedOut(100), playing(101), true, resultLoss, false, true},
+		{"They left after winning", playing(101), toppedOut(100), true, resultWin, false, true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			result, forfeit, over := resolveMatch(c.ours, c.theirs, c.left)
+			if over != c.over {
+				t.Fatalf("Expected the match to be over: %v, got %v", c.over, over)
+			}
+			if over && (result != c.result || forfeit != c.forfeit) {
+				t.Errorf("Expected %v, got %v", c.result.title(c.forfeit), result.title(forfeit))
+			}
+
+			// The opponent should see the opposite
+			theirResult, _, theirOver := resolveMatch(c.theirs, c.ours, false)
+			if !c.left && theirOver != over {
+				t.Errorf("The opponent disagrees about the match being over")
+			}
+			if !c.left && over && theirResult != opposite[result] {
+				t.Errorf("The opponent got %v to our %v", theirResult.title(false), result.title(false))
+			}
+		})
+	}
+}
+
+func TestRematchRoom(t *testing.T) {
+	// Old sessions from the match, and the next games' sessions
+	type players struct {
+		oldA, oldB, nextA, nextB *MultiplayerSession
+		cancelOldB, cancelNextA  context.CancelFunc
+	}
+	newPlayers := func() players {
+		oldB, cancelOldB := context.WithCancel(context.Background())
+		nextA, cancelNextA := context.WithCancel(context.Background())
+		return players{
+			oldA:        NewMultiplayerSession(context.Background()),
+			oldB:        NewMultiplayerSession(oldB),
+			nextA:       NewMultiplayerSession(nextA),
+			nextB:       NewMultiplayerSession(context.Background()),
+			cancelOldB:  cancelOldB,
+			cancelNextA: cancelNextA,
+		}
+	}
+
+	t.Run("Both ask", func(t *testing.T) {
+		r, p := new(rematchRoom), newPlayers()
+		opA, ok := r.ask(p.nextA, p.oldB)
+		if !ok {
+			t.Fatalf("Couldn't ask for a rematch")
+		}
+		select {
+		case <-opA:
+			t.Fatalf("Got a rematch before they asked")
+		default:
+		}
+
+		// Asking leaves your old session behind, which isn't leaving
+		p.cancelOldB()
+		opB, ok := r.ask(p.nextB, p.oldA)
+		if !ok {
+			t.Fatalf("Couldn't take them up on the rematch")
+		}
+		a, b := <-opA, <-opB
+		if a.opponent != p.nextB || b.opponent != p.nextA {
+			t.Errorf("Rematch isn't between the next games")
+		}
+		if a.seed != b.seed || a.start != b.start || a.room == nil || a.room != b.room || a.room == r {
+			t.Errorf("Players got different rematches: %+v and %+v", a, b)
+		}
+		if r.leave() {
+			t.Errorf("Left a rematch that already started")
+		}
+	})
+
+	t.Run("They leave after we ask", func(t *testing.T) {
+		r, p := new(rematchRoom), newPlayers()
+		opA, _ := r.ask(p.nextA, p.oldB)
+		if !r.leave() {
+			t.Errorf("Rematch wasn't open to leave")
+		}
+		if _, ok := <-opA; ok {
+			t.Errorf("Got a rematch against someone who left")
+		}
+		if _, ok := r.ask(p.nextB, p.oldA); ok {
+			t.Errorf("Asked for a rematch after leaving")
+		}
+	})
+
+	t.Run("They left first", func(t *testing.T) {
+		r, p := new(rematchRoom), newPlayers()
+		r.leave()
+		if _, ok := r.ask(p.nextA, p.oldB); ok {
+			t.Errorf("Asked for a rematch against someone who left")
+		}
+	})
+
+	t.Run("They disconnected", func(t *testing.T) {
+		r, p := new(rematchRoom), newPlayers()
+		p.cancelOldB()
+		if _, ok := r.ask(p.nextA, p.oldB); ok {
+			t.Errorf("Asked for a rematch against someone who disconnected")
+		}
+	})
+
+	t.Run("We gave up waiting", func(t *testing.T) {
+		r, p := new(rematchRoom), newPlayers()
+		opA, _ := r.ask(p.nextA, p.oldB)
+		p.cancelNextA()
+		if _, ok := r.ask(p.nextB, p.oldA); ok {
+			t.Errorf("Took up a rematch from someone who's gone")
+		}
+		if _, ok := <-opA; ok {
+			t.Errorf("Got a rematch after giving up")
+		}
+	})
+}
@@ -0,0 +1,190 @@
+package app
+
+import (
+	"context"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+)
+
+// How long a match counts down for before it starts
+const countdown = 3 * time.Second
+
+// A pairing from matchmaking. Both players get the same seed so they're dealt the same pieces,
+// and start at the same time
+type match struct {
+	opponent *MultiplayerSession
+	seed     uint64
+	start    time.Time
+	room     *rematchRoom // Where the two players go to play again. nil against the CPU
+}
+
+// A match starting after the countdown, plus a look tick so the player has time to notice it
+func newMatch(opponent *MultiplayerSession, seed uint64) match {
+	return match{opponent: opponent, seed: seed, start: time.Now().Add(countdown + matchLookInterval)}
+}
+
+// The same match for both a and b, each with the other as their opponent
+func pairUp(a, b *MultiplayerSession, seed uint64) (forA, forB match) {
+	forA = newMatch(b, seed)
+	forA.room = new(rematchRoom)
+	forB = forA
+	forB.opponent = a
+	return forA, forB
+}
+
+// A session's place in the queue
+type matchReq struct {
+	session *MultiplayerSession
+	opC     chan match // Gets the match, or is closed without one if the request is dropped
+	queued  time.Time
+}
+
+// How many of the last matches the estimated wait is averaged over
+const waitSamples = 10
+
+// Pairs up players looking for a VS match, first come first served. Safe to use from any goroutine
+type Matchmaker struct {
+	mx     sync.Mutex
+	queue  []*matchReq
+	waits  []time.Duration // How long the last few matched players were queued for
+	wake   chan struct{}   // Pokes Run when someone joins the queue
+	closed bool
+}
+
+// The matchmaker every session queues up in. main runs it
+var Matchmaking = NewMatchmaker()
+
+func NewMatchmaker() *Matchmaker {
+	return &Matchmaker{wake: make(chan struct{}, 1)}
+}
+
+// Queue s up for a match. Doesn't block, the match is sent through the request's opC
+// once Run has paired it with someone
+func (mm *Matchmaker) Request(s *MultiplayerSession) *matchReq {
+	req := &matchReq{session: s, opC: make(chan match, 1), queued: time.Now()}
+
+	mm.mx.Lock()
+	defer mm.mx.Unlock()
+
+	if mm.closed {
+		close(req.opC)
+		return req
+	}
+	mm.queue = append(mm.queue, req)
+
+	select {
+	case mm.wake <- struct{}{}:
+	default: // Already woken up
+	}
+	return req
+}
+
+// Take req out of the queue and close its opC. Reports whether it was still queued. If it wasn't,
+// it's already been matched or dropped, and its opC has the match or is closed
+func (mm *Matchmaker) Cancel(req *matchReq) bool {
+	mm.mx.Lock()
+	defer mm.mx.Unlock()
+
+	i := slices.Index(mm.queue, req)
+	if i < 0 {
+		return false
+	}
+	mm.queue = slices.Delete(mm.queue, i, i+1)
+	close(req.opC)
+	return true
+}
+
+// Where req is in the queue, counting from 1. 0 if it isn't queued anymore
+func (mm *Matchmaker) Position(req *matchReq) int {
+	mm.mx.Lock()
+	defer mm.mx.Unlock()
+
+	mm.prune()
+	return slices.Index(mm.queue, req) + 1
+}
+
+// How long finding a match has been taking lately. 0 if nobody's been matched yet
+func (mm *Matchmaker) EstimatedWait() time.Duration {
+	mm.mx.Lock()
+	defer mm.mx.Unlock()
+
+	if len(mm.waits) == 0 {
+		return 0
+	}
+	var total time.Duration
+	for _, w := range mm.waits {
+		total += w
+	}
+	return total / time.Duration(len(mm.waits))
+}
+
+// Pair up queued players until ctx is canceled, then drop everyone still waiting.
+// Only run one, in a goroutine in main
+func (mm *Matchmaker) Run(ctx context.Context) {
+	// Check in now and then too, so people who left don't hold their place in the queue
+	ticker := time.NewTicker(matchLookInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			mm.close()
+			return
+		case <-mm.wake:
+		case <-ticker.C:
+		}
+		mm.pair()
+	}
+}
+
+func (mm *Matchmaker) pair() {
+	mm.mx.Lock()
+	defer mm.mx.Unlock()
+
+	// Canceled sessions go first, so nobody gets matched with someone who's gone
+	mm.prune()
+
+	for len(mm.queue) >= 2 {
+		a, b := mm.queue[0], mm.queue[1]
+		mm.queue = mm.queue[2:]
+
+		log.Debug("Exchanging match requests")
+		forA, forB := pairUp(a.session, b.session, rand.Uint64())
+		// opCs are buffered, so these never block
+		a.opC <- forA
+		close(a.opC)
+		b.opC <- forB
+		close(b.opC)
+
+		mm.waits = append(mm.waits, time.Since(a.queued), time.Since(b.queued))
+		mm.waits = mm.waits[max(len(mm.waits)-waitSamples, 0):]
+	}
+}
+
+// Drop requests from canceled sessions. Needs the lock
+func (mm *Matchmaker) prune() {
+	mm.queue = slices.DeleteFunc(mm.queue, func(req *matchReq) bool {
+		if isDone(req.session) {
+			log.Debug("match request canceled")
+			close(req.opC)
+			return true
+		}
+		return false
+	})
+}
+
+// Stop taking requests and drop the ones waiting
+func (mm *Matchmaker) close() {
+	mm.mx.Lock()
+	defer mm.mx.Unlock()
+
+	mm.closed = true
+	for _, req := range mm.queue {
+		close(req.opC)
+	}
+	mm.queue = nil
+}
@@ -0,0 +1,171 @@
+package app
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"tetrissh/tetris"
+	"time"
+)
+
+func TestMatchmakerPairs(t *testing.T) {
+	cases := []struct {
+		name     string
+		players  int
+		canceled []int // Sessions canceled before pairing
+		taken    []int // Requests taken back with Cancel
+		pairs    [][2]int
+		waiting  []int // Still queued afterwards, in order
+	}{
+		{"First come first served", 4, nil, nil, [][2]int{{0, 1}, {2, 3}}, nil},
+		{"Odd one out waits", 3, nil, nil, [][2]int{{0, 1}}, []int{2}},
+		{"Canceled sessions are dropped", 4, []int{1}, nil, [][2]int{{0, 2}}, []int{3}},
+		{"Taken back", 4, nil, []int{0}, [][2]int{{1, 2}}, []int{3}},
+		{"Nobody left", 2, []int{0}, []int{1}, nil, nil},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			mm := NewMatchmaker()
+			var sessions []*MultiplayerSession
+			var reqs []*matchReq
+			for i := 0; i < c.players; i++ {
+				ctx, cancel := context.WithCancel(context.Background())
+				defer cancel()
+				if slices.Contains(c.canceled, i) {
+					cancel()
+				}
+				sessions = append(sessions, NewMultiplayerSession(ctx))
+				reqs = append(reqs, mm.Request(sessions[i]))
+			}
+			for _, i := range c.taken {
+				if !mm.Cancel(reqs[i]) {
+					t.Errorf("Request %v wasn't queued to cancel", i)
+				}
+			}
+
+			mm.pair()
+
+			for _, p := range c.pairs {
+				a, b := <-reqs[p[0]].opC, <-reqs[p[1]].opC
+				if a.opponent != sessions[p[1]] || b.opponent != sessions[p[0]] {
+					t.Errorf("Expected %v and %v to be matched with each other", p[0], p[1])
+				}
+				if a.seed != b.seed || a.start != b.start || a.room == nil || a.room != b.room {
+					t.Errorf("%v and %v got different matches: %+v and %+v", p[0], p[1], a, b)
+				}
+				if mm.Cancel(reqs[p[0]]) {
+					t.Errorf("Canceled a request that was already matched")
+				}
+			}
+			for _, i := range slices.Concat(c.canceled, c.taken) {
+				if _, ok := <-reqs[i].opC; ok {
+					t.Errorf("Request %v was matched after it was canceled", i)
+				}
+			}
+			for pos, i := range c.waiting {
+				if got := mm.Position(reqs[i]); got != pos+1 {
+					t.Errorf("Expected %v to be #%v in the queue, got #%v", i, pos+1, got)
+				}
+			}
+			if len(mm.queue) != len(c.waiting) {
+				t.Errorf("Expected %v waiting, got %v", len(c.waiting), len(mm.queue))
+			}
+		})
+	}
+}
+
+func TestMatchmakerPosition(t *testing.T) {
+	mm := NewMatchmaker()
+	first, cancel := context.WithCancel(context.Background())
+	a := mm.Request(NewMultiplayerSession(first))
+	b := mm.Request(NewMultiplayerSession(context.Background()))
+
+	if mm.Position(a) != 1 || mm.Position(b) != 2 {
+		t.Errorf("Expected positions 1 and 2, got %v and %v", mm.Position(a), mm.Position(b))
+	}
+	// Leaving moves everyone behind up
+	cancel()
+	if mm.Position(a) != 0 || mm.Position(b) != 1 {
+		t.Errorf("Expected positions 0 and 1 once the first left, got %v and %v", mm.Position(a), mm.Position(b))
+	}
+}
+
+func TestMatchmakerEstimatedWait(t *testing.T) {
+	mm := NewMatchmaker()
+	if wait := mm.EstimatedWait(); wait != 0 {
+		t.Errorf("Expected no estimate before anyone's matched, got %v", wait)
+	}
+
+	for _, waited := range []time.Duration{10 * time.Second, 20 * time.Second} {
+		a := mm.Request(NewMultiplayerSession(context.Background()))
+		b := mm.Request(NewMultiplayerSession(context.Background()))
+		a.queued = time.Now().Add(-waited)
+		b.queued = time.Now().Add(-waited)
+		mm.pair()
+	}
+
+	// Everyone's wait counts, and the pairs are averaged
+	if wait := mm.EstimatedWait().Round(time.Second); wait != 15*time.Second {
+		t.Errorf("Expected an estimated wait of 15s, got %v", wait)
+	}
+}
+
+func TestMatchmakerShutdown(t *testing.T) {
+	mm := NewMatchmaker()
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		mm.Run(ctx)
+		close(stopped)
+	}()
+
+	waiting := mm.Request(NewMultiplayerSession(context.Background()))
+	cancel()
+	<-stopped
+
+	if _, ok := <-waiting.opC; ok {
+		t.Errorf("Someone was matched with nobody on shutdown")
+	}
+	if _, ok := <-mm.Request(NewMultiplayerSession(context.Background())).opC; ok {
+		t.Errorf("Matched a request after shutting down")
+	}
+}
+
+func TestMatchStartsTogether(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a, b := newMultiplayerGame(ctx), newMultiplayerGame(ctx)
+
+	forA, forB := pairUp(a.session, b.session, 42)
+	if forA.seed != forB.seed || forA.start != forB.start {
+		t.Fatalf("Players got different matches: %+v and %+v", forA, forB)
+	}
+	if wait := time.Until(forA.start); wait < countdown || wait > countdown+matchLookInterval {
+		t.Errorf("Expected the match to start after the countdown, it starts in %v", wait)
+	}
+
+	// Start sooner than the countdown, not to wait it out
+	forA.start = time.Now().Add(100 * time.Millisecond)
+	forB.start = forA.start
+	a.setMatch(forA.opponent, forA)
+	b.setMatch(forB.opponent, forB)
+
+	if !slices.EqualFunc(a.game.Next(14), b.game.Next(14), func(p, q tetris.Piece) bool { return p.Name() == q.Name() }) {
+		t.Errorf("Players were dealt different pieces")
+	}
+
+	for _, m := range []*MultiplayerGame{a, b} {
+		m.setState()
+		if m.mstate != msCountdown {
+			t.Errorf("Expected to be counting down, got %v", m.mstate)
+		}
+	}
+	time.Sleep(time.Until(forA.start))
+	for _, m := range []*MultiplayerGame{a, b} {
+		m.setState()
+		if m.mstate != msRunning {
+			t.Errorf("Expected to be running once it's time, got %v", m.mstate)
+		}
+	}
+}
@@ -1,9 +1,13 @@
 package app
 
 import (
+	"context"
+	"tetrissh/tetris"
+
 	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/log"
 )
 
 type MenuSelectMsg struct {
@@ -28,33 +32,103 @@ func (m MenuItem) SelectCmd() tea.Cmd {
 }
 
 type MenuModel struct {
-	list  list.Model
-	style lipgloss.Style
+	list   list.Model
+	style  lipgloss.Style
+	player string
+	ctx    context.Context // The player's connection
 }
 
-func NewMenuModel() MenuModel {
-	options := []list.Item{
+// Sent to the menu when it comes back into view, so it can pick up things like new saved games
+type menuRefreshMsg struct{}
+
+func menuItems(ctx context.Context, player string) []list.Item {
+	var options []list.Item
+
+	if hasSave(player) {
+		options = append(options, MenuItem{
+			title: "Continue",
+			desc:  "Pick up your last single player game",
+			newModel: func() tea.Model {
+				s, err := ContinueSinglePlayer(ctx, player)
+				if err != nil {
+					log.Error("Couldn't load saved game, starting a new one", "error", err)
+					return NewSinglePlayer(ctx, player)
+				}
+				return s
+			},
+		})
+	}
+
+	options = append(options, MenuItem{
+		title: "Start",
+		desc:  "Single player",
+		newModel: func() tea.Model {
+			return NewSinglePlayer(ctx, player)
+		},
+	})
+
+	for _, mode := range tetris.Modes() {
+		options = append(options, MenuItem{
+			title: mode.Name,
+			desc:  mode.Description,
+			newModel: func() tea.Model {
+				return NewModeSinglePlayer(ctx, mode, player)
+			},
+		})
+	}
+
+	return append(options,
 		MenuItem{
-			title: "Start",
-			desc:  "Single player",
+			title: "Piece sets",
+			desc:  "Single player with other pieces",
 			newModel: func() tea.Model {
-				return NewSinglePlayer()
+				return NewPieceSetList(ctx, player)
+			},
+		}, MenuItem{
+			title: "Practice",
+			desc:  "Play from a fumen",
+			newModel: func() tea.Model {
+				return NewFumenInput(ctx)
+			},
+		}, MenuItem{
+			title: "Puzzles",
+			desc:  "Boards with a goal",
+			newModel: func() tea.Model {
+				return NewPuzzleList(ctx)
 			},
 		}, MenuItem{
 			title: "VS",
 			desc:  "Multiplayer",
 			newModel: func() tea.Model {
-				return NewMultiplayer()
+				return NewMultiplayer(ctx)
+			},
+		}, MenuItem{
+			title: "VS CPU",
+			desc:  "Multiplayer against a bot",
+			newModel: func() tea.Model {
+				return NewDifficultyList(ctx)
+			},
+		}, MenuItem{
+			title: "Replays",
+			desc:  "Watch finished games",
+			newModel: func() tea.Model {
+				return NewReplayList()
 			},
 		},
-	}
+	)
+}
 
-	list := list.New(options, list.NewDefaultDelegate(), 0, 0)
+// player identifies who's playing for things like saved games, empty if we don't know.
+// ctx is canceled when they disconnect
+func NewMenuModel(ctx context.Context, player string) MenuModel {
+	list := list.New(menuItems(ctx, player), list.NewDefaultDelegate(), 0, 0)
 	list.Title = "Menu"
 
 	return MenuModel{
-		list:  list,
-		style: lipgloss.NewStyle(),
+		list:   list,
+		style:  lipgloss.NewStyle(),
+		player: player,
+		ctx:    ctx,
 	}
 }
 
@@ -66,6 +140,8 @@ func (m MenuModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
 
 	switch msg := msg.(type) {
+	case menuRefreshMsg:
+		return m, m.list.SetItems(menuItems(m.ctx, m.player))
 	case tea.WindowSizeMsg:
 		h, v := m.style.GetFrameSize()
 		m.list.SetSize(msg.Width-h, msg.Height-v)
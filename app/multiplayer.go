@@ -3,10 +3,10 @@ package app
 import (
 	"context"
 	"fmt"
-	"sync"
+	"math/rand/v2"
+	"tetrissh/tetris"
 	"time"
 
-	"github.com/charmbracelet/bubbles/progress"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/charmbracelet/log"
@@ -16,7 +16,9 @@ type matchState int
 
 const (
 	msLooking matchState = iota
+	msCountdown
 	msRunning
+	msOver // Somebody won, or it was a draw
 	msCanceled
 )
 
@@ -24,8 +26,12 @@ func (m matchState) String() string {
 	switch m {
 	case msLooking:
 		return "looking for match"
+	case msCountdown:
+		return "match starting"
 	case msRunning:
 		return "match active"
+	case msOver:
+		return "match over"
 	case msCanceled:
 		return "match canceled"
 	default:
@@ -33,54 +39,151 @@ func (m matchState) String() string {
 	}
 }
 
+// The other player in a VS match, either another session's MultiplayerSession or a CPUOpponent
+type Opponent interface {
+	GameInfo
+	// Garbage we attacked with, for the opponent to queue up in their game
+	SendGarbage(lines int)
+	Stats() MatchStats
+	done() <-chan struct{}
+	// Anything that went wrong reading the opponent's game
+	Err() error
+}
+
+// How long to look for a person to play before settling for the CPU
+const matchTimeout = 30 * time.Second
+
 type MultiplayerGame struct {
+	ctx       context.Context // The player's connection, every session the game starts ends with it
 	cancel    context.CancelFunc
 	session   *MultiplayerSession
-	opSession *MultiplayerSession
-	opC       <-chan *MultiplayerSession // Channel for the matchmaking goroutine to send the opponent to
+	opponent  Opponent
+	req       *matchReq    // Our place in matchmaking, while we're looking
+	opC       <-chan match // Channel for matchmaking (or a rematch) to send the match to
 	game      *GameModel
-	scoreBar  *progress.Model
 	mstate    matchState
+	started   time.Time           // When we started looking for a match
+	start     time.Time           // When the match starts, after the countdown
+	cpu       *Difficulty         // Set when playing the CPU
+	room      *rematchRoom        // The match's, or the last match's while we wait on a rematch
+	rematchOf *MultiplayerSession // Who we asked for a rematch, while we wait on them
+	result    matchResult
+	forfeit   bool
+	selected  int // Option highlighted on the results screen
+	// Both sides' stats as of when the match was decided
+	ours, theirs MatchStats
+}
+
+// Look for another player, and play the CPU if nobody shows up within matchTimeout.
+// The match is canceled when ctx is, like when the player's connection drops
+func NewMultiplayer(ctx context.Context) *MultiplayerGame {
+	m := newMultiplayerGame(ctx)
+	m.req = Matchmaking.Request(m.session)
+	m.opC = m.req.opC
+	m.started = time.Now()
+	return m
 }
 
-func NewMultiplayer() *MultiplayerGame {
-	ctx, cancel := context.WithCancel(context.Background())
-	var board *[][]int
+// Play against the CPU right away
+func NewCPUMultiplayer(ctx context.Context, d Difficulty) *MultiplayerGame {
+	m := newMultiplayerGame(ctx)
+	m.playCPU(d)
+	return m
+}
 
-	// TODO: NewMultiplayerSession
-	session := &MultiplayerSession{
-		ctx:   ctx,
-		board: board,
-		mx:    new(sync.RWMutex),
-	}
+func newMultiplayerGame(parent context.Context) *MultiplayerGame {
+	ctx, cancel := context.WithCancel(parent)
+	session := NewMultiplayerSession(ctx)
 
-	gm := NewGameModel()
+	gm := NewGameModel(rand.Uint64())
 	// initialize the board pointer, it shouldn't be nil unless the game has been closed
 	session.SetBoard(gm.Board())
 
-	opC := session.requestMatch()
+	return &MultiplayerGame{
+		ctx:     parent,
+		session: session,
+		cancel:  cancel,
+		game:    &gm,
+	}
+}
 
-	scoreM := progress.New(
-		progress.WithGradient("#030ffc", "#fa0202"),
-		progress.WithoutPercentage(),
-	)
+// Stop looking for a person and start a CPU opponent instead
+func (m *MultiplayerGame) playCPU(d Difficulty) {
+	// Out of matchmaking, and the game carries on with a new session
+	m.stopLooking()
+	m.cancel()
+	ctx, cancel := context.WithCancel(m.ctx)
+	m.cancel = cancel
+	m.session = NewMultiplayerSession(ctx)
+
+	m.opC = nil
+	m.cpu = &d
+	// The CPU gets the same pieces too
+	mt := newMatch(nil, rand.Uint64())
+	m.setMatch(NewCPUOpponent(ctx, d, mt.seed, mt.start, m.session), mt)
+}
+
+// Start over on the match's seed, timed to start with the opponent's game. The game runs on the
+// server from then on, and a player who stops responding forfeits
+func (m *MultiplayerGame) setMatch(opponent Opponent, mt match) {
+	g := tetris.NewGame(boardHeight, boardWidth, mt.seed, tetris.DefaultRules())
+	m.session.SetBoard(g.Board())
+	m.session.SetStats(gameStats(g, 0))
+
+	runner := NewGameRunner(m.session.ctx, g, mt.start, exchangeGarbage(m.session, opponent), m.cancel)
+	m.game = &GameModel{Game: &g, runner: runner}
+	m.opponent = opponent
+	m.start = mt.start
+	m.room = mt.room
+	m.rematchOf = nil
+}
+
+// Send our attacks to the opponent, queue up what they sent us, and keep our session up to date
+// for them. Runs on the game's runner, so none of it waits on the player's UI
+func exchangeGarbage(session *MultiplayerSession, opponent Opponent) stepHook {
+	sent := 0
 
-	game := &MultiplayerGame{
-		session:  session,
-		cancel:   cancel,
-		scoreBar: &scoreM,
-		opC:      opC,
-		game:     &gm,
+	return func(g *tetris.Game, events []tetris.Event) {
+		for _, e := range events {
+			if a, ok := e.(tetris.Attack); ok && a.Lines > 0 {
+				opponent.SendGarbage(a.Lines)
+				sent += a.Lines
+			}
+		}
+		for _, lines := range session.takeGarbage() {
+			g.QueueGarbage(lines, rand.IntN(boardWidth))
+		}
+
+		session.SetBoard(g.Board())
+		session.SetStats(gameStats(*g, sent))
 	}
+}
 
-	return game
+// Take us out of the matchmaking queue, or back out of the rematch we asked for.
+// False if it was too late, and whatever we were waiting on has already answered through opC
+func (m *MultiplayerGame) stopLooking() bool {
+	stopped := true
+	if m.req != nil {
+		stopped = Matchmaking.Cancel(m.req)
+		m.req = nil
+	}
+	if m.rematchOf != nil {
+		stopped = m.room.leave()
+		m.rematchOf = nil
+	}
+	return stopped
 }
 
 func (m *MultiplayerGame) close() {
 	log.Debug("Closing game")
+	m.stopLooking()
+	if m.room != nil {
+		// Not sticking around for a rematch
+		m.room.leave()
+	}
 	m.cancel()
 	// drop shared pointers, might not be necessary
-	m.opSession = nil
+	m.opponent = nil
 	m.session = nil
 }
 
@@ -89,23 +192,30 @@ func (m *MultiplayerGame) close() {
 func (m *MultiplayerGame) setState() {
 	oldState := m.mstate
 
-	if oldState != msCanceled {
+	if oldState != msCanceled && oldState != msOver {
 		newState := m.mstate
 
-		if m.opSession == nil {
-			// Why does this throw nil pointer deref error if oldState == msRunning is inlined with above?
-			if oldState == msRunning {
+		if m.opponent == nil {
+			// Closed, or canceled before anyone was found
+			if m.session == nil || isDone(m.session) {
 				newState = msCanceled
 			}
 		} else {
+			ours, theirs := m.session.Stats(), m.opponent.Stats()
+			result, forfeit, over := resolveMatch(ours, theirs, isDone(m.opponent))
+
 			select {
-			// Check if either session in the match is canceled
-			case <-m.opSession.done():
-				newState = msCanceled
 			case <-m.session.done(): // Shouldn't really be reached but just in case
 				newState = msCanceled
-			default: // If not, make sure mstate is running
-				if oldState == msLooking {
+			default:
+				if over {
+					m.result, m.forfeit = result, forfeit
+					m.ours, m.theirs = ours, theirs
+					m.finish()
+					newState = msOver
+				} else if oldState == msLooking {
+					newState = msCountdown
+				} else if oldState == msCountdown && !time.Now().Before(m.start) {
 					log.Debug("Setting multiplayer game to running, initializing fall tick")
 					newState = msRunning
 				}
@@ -116,24 +226,49 @@ func (m *MultiplayerGame) setState() {
 	}
 }
 
-// Update this game's session board. Thread safe, blocks on mutex
-func (m *MultiplayerGame) SetBoard() {
-	m.session.SetBoard(m.game.Board())
+// Stop both games once the match is decided, so nothing changes on the results screen
+func (m *MultiplayerGame) finish() {
+	m.game.stop()
+	if c, ok := m.opponent.(*CPUOpponent); ok {
+		c.stop()
+	}
+}
+
+func isDone(o Opponent) bool {
+	select {
+	case <-o.done():
+		return true
+	default:
+		return false
+	}
 }
 
 type MatchLookTickMsg struct{}
 
+const matchLookInterval = time.Second / 4
+
 func MatchLookTick() tea.Cmd {
-	return tea.Tick(time.Second*2, func(t time.Time) tea.Msg {
+	return tea.Tick(matchLookInterval, func(t time.Time) tea.Msg {
 		return MatchLookTickMsg{}
 	})
 }
 
+// Redraws the countdown until the match starts
+type countdownTickMsg struct{}
+
+func countdownTick() tea.Cmd {
+	return tea.Tick(time.Second/20, func(t time.Time) tea.Msg {
+		return countdownTickMsg{}
+	})
+}
+
 func (m MultiplayerGame) Init() tea.Cmd {
+	if m.opponent != nil {
+		return countdownTick()
+	}
 	return MatchLookTick()
 }
 
-// FIXME: Doesn't properly cancel multiplayer match if session is terminated OOB (not C-c or q)
 func (m MultiplayerGame) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
 	m.setState()
@@ -144,91 +279,137 @@ func (m MultiplayerGame) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if m.mstate == msLooking {
 			// Once an opponent session has been sent, set the opponent
 			select {
-			case s, ok := <-m.opC:
+			case mt, ok := <-m.opC:
 				if !ok {
-					log.Error("Tried to read from a closed opC on a MatchLook msg")
-					// TODO: Return error msg cmd here
-					return m, nil
+					if isDone(m.session) {
+						log.Debug("Match request closed without a match")
+						return m, nil
+					}
+					// The rematch fell through, so find someone else
+					log.Debug("No rematch, looking for a match")
+					m.rematchOf = nil
+					m.req = Matchmaking.Request(m.session)
+					m.opC = m.req.opC
+					return m, MatchLookTick()
 				} else {
-					m.opSession = s
-					return m, m.game.Init()
+					m.req = nil
+					m.setMatch(mt.opponent, mt)
+					return m, countdownTick()
 				}
 			default:
+				if m.rematchOf != nil && isDone(m.rematchOf) {
+					// They didn't stay for it. If they'd said yes, the match is waiting in opC
+					m.room.leave()
+				}
+				if time.Since(m.started) >= matchTimeout {
+					// Somebody might've turned up just now
+					if !m.stopLooking() {
+						if mt, ok := <-m.opC; ok {
+							m.setMatch(mt.opponent, mt)
+							return m, countdownTick()
+						}
+					}
+					log.Debug("Nobody to match with, playing the CPU")
+					m.playCPU(DifficultyMedium)
+					return m, countdownTick()
+				}
 				return m, MatchLookTick()
 			}
 		}
+	case countdownTickMsg:
+		switch m.mstate {
+		case msCountdown:
+			return m, countdownTick()
+		case msRunning:
+			return m, m.game.Init()
+		}
 	case tea.KeyMsg:
 		switch msg.String() {
-		case "q", "ctrl+c":
+		case "q", "ctrl+c", "esc":
 			m.close()
 			return m, DeactivateCmd
 		default:
+			if m.mstate == msOver {
+				return m, m.updateResults(msg)
+			}
 			if m.mstate == msRunning {
 				*m.game, cmd = m.game.Update(msg)
-				m.SetBoard()
 			}
 		}
-	case FallMsg:
+	case FrameMsg:
+		if m.mstate == msOver {
+			break // Stopped where the match was decided
+		}
 		*m.game, cmd = m.game.Update(msg)
-		m.SetBoard()
+		// Keep checking on the opponent until the match is decided
+		if m.game.GameOver && m.mstate == msRunning {
+			cmd = FrameTickCmd()
+		}
 	}
 	return m, cmd
 }
 
-// Returns a # between 0.0 <-> 1.0 representing the ration between this player's score and the other
-// Returns 0.5, err if we can't get score.
-func (m *MultiplayerGame) scoreRatio() (float64, error) {
-	if m.session == nil || m.opSession == nil {
-		msg := "Tried to call scorePercent on a game with a nil session or opSession pointer"
-		return 0.5, fmt.Errorf(msg)
-	}
-
-	score := m.session.Score()
-	opScore := m.opSession.Score()
-
-	if score+opScore == 0 { // Ratio is "even" if both scores are 0. Guard against Div by 0
-		return 0.5, nil
-	}
-
-	return float64(score) / float64(score+opScore), nil
-}
-
 func (m *MultiplayerGame) renderGame() string {
 	// Layout is as such:
-	// Score bar
 	// Your score | Their score
-	// Your Board | Their board
+	// Garbage meter | Your Board | Their board
+	scoresView := lipgloss.JoinHorizontal(lipgloss.Top, ScoreView(m.game), ScoreView(m.opponent))
 	boardsView := lipgloss.JoinHorizontal(
-		lipgloss.Left,
-		BoardView(m.game),
-		BoardView(m.opSession),
+		lipgloss.Top,
+		GarbageMeter(m.game.PendingGarbage(), boardHeight),
+		m.game.PlayfieldView(),
+		BoardView(m.opponent),
 	)
 
-	boardW := lipgloss.Width(boardsView)
-	m.scoreBar.Width = boardW
+	return lipgloss.JoinVertical(lipgloss.Left, scoresView, boardsView)
+}
+
+func (m *MultiplayerGame) lookingView() string {
+	lines := []string{"looking for match"}
 
-	if r, err := m.scoreRatio(); err != nil {
-		panic(err)
-	} else {
-		scoreView := m.scoreBar.ViewAs(r)
-		return lipgloss.JoinVertical(lipgloss.Left, scoreView, boardsView)
+	// Rematches wait on the opponent rather than the queue
+	if m.rematchOf != nil {
+		lines[0] = "waiting for your opponent to say yes to the rematch"
 	}
+	if m.req != nil {
+		if pos := Matchmaking.Position(m.req); pos > 0 {
+			lines = append(lines, fmt.Sprintf("#%v in the queue", pos))
+		}
+		if wait := Matchmaking.EstimatedWait(); wait > 0 {
+			lines = append(lines, fmt.Sprintf("matches have been taking about %v", wait.Round(time.Second)))
+		}
+	}
+
+	left := matchTimeout - time.Since(m.started).Truncate(time.Second)
+	return lipgloss.JoinVertical(lipgloss.Left, append(lines,
+		fmt.Sprintf("playing the CPU in %v if nobody shows up", max(left, 0)),
+		"",
+		"esc to stop looking",
+	)...)
 }
 
 func (m MultiplayerGame) View() string {
 	m.setState()
 	switch m.mstate {
 	case msLooking:
-		return "looking for match"
+		return m.lookingView()
+	case msCountdown:
+		left := time.Until(m.start)
+		text := "Get ready"
+		if left <= countdown {
+			text = fmt.Sprint(int((left + time.Second - 1) / time.Second))
+		}
+		return lipgloss.JoinVertical(lipgloss.Center, m.renderGame(), text)
 	case msRunning:
-		if err := m.opSession.err; err != nil {
+		if err := m.opponent.Err(); err != nil {
 			// TODO: Render error message
 			msg := fmt.Sprintf("Error when trying to view an opSession: %v", err)
 			log.Errorf(msg)
 			panic(msg) // TODO: just display the message
 		}
 		return m.renderGame()
-
+	case msOver:
+		return m.renderResults()
 	case msCanceled:
 		return "match canceled"
 	default:
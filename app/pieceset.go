@@ -0,0 +1,67 @@
+package app
+
+import (
+	"context"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strings"
+	"tetrissh/tetris"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/log"
+)
+
+// Where extra piece sets are loaded from, set with the server's -pieces flag
+var PieceSetDir = "pieces"
+
+// Register every piece set in PieceSetDir. Call once at startup, before anyone's playing.
+// Sets that can't be loaded are skipped
+func LoadPieceSets() {
+	paths, err := filepath.Glob(filepath.Join(PieceSetDir, "*.json"))
+	if err != nil {
+		log.Error("Couldn't list piece sets", "error", err)
+	}
+
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			log.Warn("Skipping piece set", "path", path, "error", err)
+			continue
+		}
+		set, err := tetris.ParsePieceSet(data)
+		if err != nil {
+			log.Warn("Skipping piece set", "path", path, "error", err)
+			continue
+		}
+
+		tetris.RegisterPieceSet(set)
+		log.Info("Loaded piece set", "name", set.Name, "pieces", len(set.Pieces))
+	}
+}
+
+// Every registered piece set, to start a single player game with
+func NewPieceSetList(ctx context.Context, player string) ItemList {
+	var items []list.Item
+	for _, name := range tetris.PieceSetNames() {
+		set, _ := tetris.PieceSetNamed(name)
+
+		var names []string
+		for _, p := range set.Pieces {
+			names = append(names, p.Name())
+		}
+
+		items = append(items, MenuItem{
+			title: name,
+			desc:  strings.Join(names, " "),
+			newModel: func() tea.Model {
+				rules := tetris.DefaultRules()
+				rules.PieceSet = name
+				return newSinglePlayer(ctx, tetris.NewGame(boardHeight, boardWidth, rand.Uint64(), rules), player)
+			},
+		})
+	}
+
+	return NewItemList("Piece sets", items)
+}
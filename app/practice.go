@@ -0,0 +1,76 @@
+package app
+
+import (
+	"context"
+	"math/rand/v2"
+	"tetrissh/tetris"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+var errorStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("001"))
+
+// Asks for a fumen, then starts a practice game on its board with its pieces dealt first.
+// Practice games aren't saved for continuing, but do get a replay
+type FumenInput struct {
+	ctx   context.Context // The player's connection, which the game stops with
+	input textinput.Model
+	err   error
+}
+
+func NewFumenInput(ctx context.Context) FumenInput {
+	input := textinput.New()
+	input.Placeholder = "v115@..."
+	input.Width = 40
+	input.Focus()
+
+	return FumenInput{ctx: ctx, input: input}
+}
+
+func (f FumenInput) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+func (f FumenInput) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "enter":
+			g, err := practiceGame(f.input.Value())
+			if err != nil {
+				f.err = err
+				return f, nil
+			}
+
+			s := newSinglePlayer(f.ctx, g, "")
+			return f, func() tea.Msg { return MenuSelectMsg{model: s} }
+		case "esc", "ctrl+c":
+			return f, DeactivateCmd
+		}
+	}
+
+	f.input, cmd = f.input.Update(msg)
+	return f, cmd
+}
+
+func practiceGame(fumen string) (tetris.Game, error) {
+	setup, err := tetris.DecodeFumen(fumen, boardHeight, boardWidth)
+	if err != nil {
+		return tetris.Game{}, err
+	}
+	return tetris.NewGameFrom(boardHeight, boardWidth, rand.Uint64(), tetris.DefaultRules(), setup)
+}
+
+func (f FumenInput) View() string {
+	views := []string{"Paste a fumen to practice on", f.input.View()}
+	if f.err != nil {
+		views = append(views, errorStyle.Render(f.err.Error()))
+	}
+	views = append(views, "enter to start, esc to go back")
+
+	return lipgloss.JoinVertical(lipgloss.Left, views...)
+}
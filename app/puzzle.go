@@ -0,0 +1,204 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"tetrissh/tetris"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/log"
+)
+
+// Where puzzle files are loaded from, set with the server's -puzzles flag
+var PuzzleDir = "puzzles"
+
+// A puzzle file. The board comes from either Fumen or Board, Board being rows of text
+// like tetris.ParseBoard takes. Pieces come from Sequence, or from the fumen if there's no Sequence.
+// Only those pieces are dealt
+type Puzzle struct {
+	Name        string
+	Description string
+	Fumen       string
+	Board       []string
+	Sequence    []string
+	Hold        string
+	Goal        tetris.Goal
+}
+
+func loadPuzzle(path string) (Puzzle, error) {
+	var p Puzzle
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return p, err
+	}
+	if err := json.Unmarshal(data, &p); err != nil {
+		return p, err
+	}
+
+	if p.Name == "" {
+		p.Name = strings.TrimSuffix(filepath.Base(path), ".json")
+	}
+	// Make sure it can be played before it's listed
+	_, err = p.newGame()
+	return p, err
+}
+
+// Start the puzzle over from the beginning
+func (p Puzzle) newGame() (tetris.Game, error) {
+	setup := tetris.Setup{Sequence: p.Sequence, Hold: p.Hold, Only: true}
+
+	if p.Fumen != "" {
+		f, err := tetris.DecodeFumen(p.Fumen, boardHeight, boardWidth)
+		if err != nil {
+			return tetris.Game{}, err
+		}
+		setup.Board = f.Board
+		if len(setup.Sequence) == 0 {
+			setup.Sequence, setup.Hold = f.Sequence, f.Hold
+		}
+	} else {
+		board, err := tetris.ParseBoard(boardHeight, boardWidth, p.Board...)
+		if err != nil {
+			return tetris.Game{}, err
+		}
+		setup.Board = board
+	}
+
+	rules := tetris.DefaultRules()
+	rules.Goal = p.Goal
+	// Only the sequence gets dealt, so the seed doesn't matter
+	return tetris.NewGameFrom(boardHeight, boardWidth, 0, rules, setup)
+}
+
+// What the goal asks for, like "T-spin double within 1 piece"
+func goalText(g tetris.Goal) string {
+	var parts []string
+	if g.Lines > 0 {
+		parts = append(parts, fmt.Sprintf("clear %v lines", g.Lines))
+	}
+	if g.Spin != tetris.SpinNone {
+		parts = append(parts, tetris.Clear{Spin: g.Spin, Rows: make([]int, g.SpinLines)}.Name())
+	}
+	if g.PerfectClear {
+		parts = append(parts, "perfect clear")
+	}
+
+	text := strings.Join(parts, ", ")
+	if g.Pieces > 0 {
+		text += fmt.Sprintf(" within %v pieces", g.Pieces)
+	}
+	return strings.TrimSpace(text)
+}
+
+/*** PUZZLE LIST ***/
+
+// Lists every puzzle in PuzzleDir by file name. Puzzles that can't be loaded are skipped
+func NewPuzzleList(ctx context.Context) ItemList {
+	paths, err := filepath.Glob(filepath.Join(PuzzleDir, "*.json"))
+	if err != nil {
+		log.Error("Couldn't list puzzles", "error", err)
+	}
+
+	var items []list.Item
+	for _, path := range paths {
+		p, err := loadPuzzle(path)
+		if err != nil {
+			log.Warn("Skipping puzzle", "path", path, "error", err)
+			continue
+		}
+
+		desc := p.Description
+		if desc == "" {
+			desc = goalText(p.Goal)
+		}
+		items = append(items, MenuItem{
+			title: p.Name,
+			desc:  desc,
+			newModel: func() tea.Model {
+				return NewPuzzleModel(ctx, p)
+			},
+		})
+	}
+
+	return NewItemList("Puzzles", items)
+}
+
+/*** PUZZLE ***/
+
+// Plays one puzzle, and lets it be retried as many times as it takes
+type PuzzleModel struct {
+	ctx    context.Context // The player's connection, which the game stops with
+	puzzle Puzzle
+	gm     *GameModel
+}
+
+// p has to have come from loadPuzzle, which already checked it can be played
+func NewPuzzleModel(ctx context.Context, p Puzzle) PuzzleModel {
+	m := PuzzleModel{ctx: ctx, puzzle: p}
+	m.retry()
+	return m
+}
+
+func (m *PuzzleModel) retry() {
+	if m.gm != nil {
+		m.gm.stop()
+	}
+	g, _ := m.puzzle.newGame()
+	gm := runGameModel(m.ctx, g)
+	m.gm = &gm
+}
+
+func (m PuzzleModel) Init() tea.Cmd {
+	return m.gm.Init()
+}
+
+func (m PuzzleModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "q", "ctrl+c":
+			m.gm.stop()
+			return m, DeactivateCmd
+		case "ctrl+r":
+			// The old game's frames keep ticking, unless it was already over
+			over := m.gm.GameOver
+			m.retry()
+			if over {
+				cmd = m.gm.Init()
+			}
+			return m, cmd
+		}
+	}
+
+	*m.gm, cmd = m.gm.Update(msg)
+	return m, cmd
+}
+
+func (m PuzzleModel) View() string {
+	header := lipgloss.JoinVertical(lipgloss.Center, m.puzzle.Name, goalText(m.puzzle.Goal))
+
+	var footer string
+	switch m.gm.Outcome() {
+	case tetris.OutcomeWon:
+		footer = "Solved! ctrl+r to play again, q to go back"
+	case tetris.OutcomeLost:
+		footer = "Not quite. ctrl+r to retry, q to go back"
+	default:
+		footer = fmt.Sprintf("Piece %v", m.gm.Placed()+1)
+		if m.puzzle.Goal.Pieces > 0 {
+			footer += fmt.Sprintf("/%v", m.puzzle.Goal.Pieces)
+		}
+		footer += ", ctrl+r to retry"
+	}
+
+	return lipgloss.JoinVertical(lipgloss.Center, header, m.gm.View(), footer)
+}
@@ -0,0 +1,177 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"tetrissh/tetris"
+	"time"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/log"
+)
+
+// Where finished games are saved, and where the replay list looks for them
+var ReplayDir = "replays"
+
+// Write a replay into ReplayDir, named so the newest sorts last. Games that end
+// in the same second get a random bit on the end so they don't overwrite each other
+func saveReplay(r tetris.Replay) error {
+	data, err := r.Marshal()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(ReplayDir, 0o755); err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(ReplayDir, fmt.Sprintf("%v-%v-*.json", time.Now().Unix(), r.Score))
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func loadReplay(path string) (tetris.Replay, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return tetris.Replay{}, err
+	}
+	return tetris.ParseReplay(data)
+}
+
+/*** REPLAY LIST ***/
+
+// Lists every replay in ReplayDir, newest first. Unreadable files are skipped
+func NewReplayList() ItemList {
+	paths, err := filepath.Glob(filepath.Join(ReplayDir, "*.json"))
+	if err != nil {
+		log.Error("Couldn't list replays", "error", err)
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(paths)))
+
+	var items []list.Item
+	for _, path := range paths {
+		r, err := loadReplay(path)
+		if err != nil {
+			log.Warn("Skipping replay", "path", path, "error", err)
+			continue
+		}
+
+		items = append(items, MenuItem{
+			title: fmt.Sprintf("Score %v", r.Score),
+			desc:  fmt.Sprintf("%v lines in %v, %v", r.Lines, formatFrames(r.Frames), strings.TrimSuffix(filepath.Base(path), ".json")),
+			newModel: func() tea.Model {
+				return NewReplayModel(r)
+			},
+		})
+	}
+
+	return NewItemList("Replays", items)
+}
+
+/*** REPLAY PLAYER ***/
+
+var replaySpeeds = []float64{0.5, 1, 2, 4}
+
+// How far left/right seeks
+const seekFrames = 5 * tetris.FramesPerSecond
+
+// Plays a replay back through a GameModel, with pause, speed and seek controls
+type ReplayModel struct {
+	replay tetris.Replay
+	gm     *GameModel
+	speed  int // Index into replaySpeeds
+	paused bool
+	owed   float64 // Frames the engine is behind by, at speeds under 1x this builds up between ticks
+}
+
+func NewReplayModel(r tetris.Replay) ReplayModel {
+	return ReplayModel{
+		replay: r,
+		gm:     newReplayGame(r),
+		speed:  1,
+	}
+}
+
+// r has to have come from loadReplay, which already checked it can be set up
+func newReplayGame(r tetris.Replay) *GameModel {
+	g, _ := r.NewGame()
+	return &GameModel{Game: &g}
+}
+
+func (r ReplayModel) Init() tea.Cmd {
+	return FrameTickCmd()
+}
+
+// Jump to frame, starting over from the beginning if it's behind us
+func (r *ReplayModel) seek(frame int) {
+	frame = min(max(frame, 0), r.replay.Frames)
+
+	if frame < r.gm.Frames() {
+		lastFrame := r.gm.lastFrame
+		r.gm = newReplayGame(r.replay)
+		r.gm.lastFrame = lastFrame
+	}
+
+	r.gm.handleEvents(r.replay.Advance(r.gm.Game, frame-r.gm.Frames()))
+}
+
+func (r ReplayModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case FrameMsg:
+		// Keep the clock going while paused so unpausing doesn't jump ahead
+		frames := r.gm.elapsedFrames(msg.t)
+		if !r.paused {
+			r.owed += float64(frames) * replaySpeeds[r.speed]
+			frames = int(r.owed)
+			r.owed -= float64(frames)
+			r.gm.handleEvents(r.replay.Advance(r.gm.Game, frames))
+		}
+		return r, FrameTickCmd()
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "q", "ctrl+c":
+			return r, DeactivateCmd
+		case " ", "p":
+			r.paused = !r.paused
+		case "+", "=":
+			r.speed = min(r.speed+1, len(replaySpeeds)-1)
+		case "-":
+			r.speed = max(r.speed-1, 0)
+		case "h", "left":
+			r.seek(r.gm.Frames() - seekFrames)
+		case "l", "right":
+			r.seek(r.gm.Frames() + seekFrames)
+		}
+	}
+
+	return r, nil
+}
+
+// m:ss.cc. Games only count whole frames, so the hundredths go up a frame (about 0.017s) at a time
+func formatFrames(frames int) string {
+	d := time.Duration(frames) * time.Second / tetris.FramesPerSecond
+	return fmt.Sprintf("%d:%02d.%02d", int(d.Minutes()), int(d.Seconds())%60, d.Milliseconds()%1000/10)
+}
+
+func (r ReplayModel) View() string {
+	state := "▶"
+	if r.paused {
+		state = "⏸"
+	}
+
+	controls := fmt.Sprintf("%v %vx  %v / %v", state, replaySpeeds[r.speed], formatFrames(r.gm.Frames()), formatFrames(r.replay.Frames))
+	help := "space pause, +/- speed, ←/→ seek, q quit"
+
+	return lipgloss.JoinVertical(lipgloss.Center, r.gm.View(), controls, help)
+}
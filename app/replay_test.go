@@ -0,0 +1,30 @@
+package app
+
+import (
+	"path/filepath"
+	"testing"
+	"tetrissh/tetris"
+)
+
+func TestSaveReplaySameSecond(t *testing.T) {
+	defer func(dir string) { ReplayDir = dir }(ReplayDir)
+	ReplayDir = t.TempDir()
+
+	// Same game saved back to back, so it's all the same second and score
+	r := tetris.NewGame(boardHeight, boardWidth, 0, tetris.DefaultRules()).Replay()
+	for i := 0; i < 3; i++ {
+		if err := saveReplay(r); err != nil {
+			t.Fatalf("Couldn't save replay: %v", err)
+		}
+	}
+
+	paths, _ := filepath.Glob(filepath.Join(ReplayDir, "*.json"))
+	if len(paths) != 3 {
+		t.Fatalf("Expected 3 replays, got %v", len(paths))
+	}
+	for _, path := range paths {
+		if _, err := loadReplay(path); err != nil {
+			t.Errorf("Couldn't load %v back: %v", path, err)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"tetrissh/tetris"
+	"time"
+
+	"github.com/charmbracelet/log"
+)
+
+// How long a game keeps going without its UI asking for a snapshot, before the player is taken to be gone.
+// Only changed by tests
+var disconnectTimeout = 10 * time.Second
+
+// Runs after every step on the runner's goroutine, with everything that happened in it.
+// It's the place to do things that have to keep happening even if the player's UI stalls,
+// like trading garbage with an opponent
+type stepHook func(g *tetris.Game, events []tetris.Event)
+
+// Plays a game on its own goroutine, stepped by its own ticker, so the game keeps time even
+// when the bubbletea program showing it falls behind. The UI sends inputs with Act and reads
+// the game back with Snapshot
+type GameRunner struct {
+	ctx      context.Context
+	cancel   context.CancelFunc
+	mx       sync.Mutex
+	inputs   []tetris.Action // Waiting for the next step
+	snapshot tetris.Game
+	events   []tetris.Event // Since the last Snapshot
+	lastPoll time.Time
+	timeout  time.Duration // disconnectTimeout when it started
+	err      error         // Set if the game crashed
+}
+
+// Start running g at start, until ctx is canceled, the game ends, or the UI stops asking for snapshots.
+// gone is called in that last case, and if the game crashes. hook and gone can be nil
+func NewGameRunner(ctx context.Context, g tetris.Game, start time.Time, hook stepHook, gone func()) *GameRunner {
+	ctx, cancel := context.WithCancel(ctx)
+	r := &GameRunner{
+		ctx:      ctx,
+		cancel:   cancel,
+		snapshot: g.Clone(),
+		lastPoll: time.Now(),
+		timeout:  disconnectTimeout,
+	}
+
+	go r.run(g, start, hook, gone)
+	return r
+}
+
+func (r *GameRunner) run(g tetris.Game, start time.Time, hook stepHook, gone func()) {
+	defer r.cancel()
+	// One broken game shouldn't take the whole server down with it
+	defer func() {
+		if p := recover(); p != nil {
+			log.Error("Game crashed", "panic", p, "stack", string(debug.Stack()))
+			r.mx.Lock()
+			r.err = fmt.Errorf("the game crashed: %v", p)
+			r.mx.Unlock()
+			if gone != nil {
+				gone()
+			}
+		}
+	}()
+
+	select {
+	case <-r.ctx.Done():
+		return
+	case <-time.After(time.Until(start)):
+	}
+
+	ticker := time.NewTicker(frameDuration)
+	defer ticker.Stop()
+	// Frames are counted from the wall clock, so ticks arriving late don't slow the game down
+	lastFrame := time.Now()
+
+	for !g.GameOver {
+		var now time.Time
+		select {
+		case <-r.ctx.Done():
+			return
+		case now = <-ticker.C:
+		}
+
+		frames := int(now.Sub(lastFrame) / frameDuration)
+		lastFrame = lastFrame.Add(time.Duration(frames) * frameDuration)
+
+		r.mx.Lock()
+		inputs := r.inputs
+		r.inputs = nil
+		lastPoll := r.lastPoll
+		r.mx.Unlock()
+
+		if time.Since(lastPoll) > r.timeout {
+			log.Info("Game's UI stopped responding, ending it")
+			if gone != nil {
+				gone()
+			}
+			return
+		}
+
+		events := g.Step(frames, inputs)
+		if hook != nil {
+			hook(&g, events)
+			// Whatever the hook did to the game happened too
+			events = append(events, g.Events()...)
+		}
+
+		r.mx.Lock()
+		r.snapshot = g.Clone()
+		r.events = append(r.events, events...)
+		r.mx.Unlock()
+	}
+}
+
+// Press something in the game. It happens on the runner's next step
+func (r *GameRunner) Act(a tetris.Action) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	r.inputs = append(r.inputs, a)
+}
+
+// A copy of the game as of the last step, and everything that happened since the last call.
+// Calling it regularly is how the runner knows the player is still there
+func (r *GameRunner) Snapshot() (tetris.Game, []tetris.Event) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	r.lastPoll = time.Now()
+	events := r.events
+	r.events = nil
+	return r.snapshot, events
+}
+
+// Stop the game where it is
+func (r *GameRunner) Stop() {
+	r.cancel()
+}
+
+// Why the runner stopped early, if it crashed
+func (r *GameRunner) Err() error {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	return r.err
+}
+
+// Closed once the runner has stopped, whether it finished, was stopped or gave up on the player
+func (r *GameRunner) done() <-chan struct{} {
+	return r.ctx.Done()
+}
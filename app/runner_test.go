@@ -0,0 +1,118 @@
+package app
+
+import (
+	"context"
+	"testing"
+	"tetrissh/tetris"
+	"time"
+)
+
+func TestGameRunnerCrash(t *testing.T) {
+	g := tetris.NewGame(boardHeight, boardWidth, 0, tetris.DefaultRules())
+	gone := make(chan struct{})
+	r := NewGameRunner(context.Background(), g, time.Now(), func(*tetris.Game, []tetris.Event) {
+		panic("broken hook")
+	}, func() { close(gone) })
+
+	select {
+	case <-gone:
+	case <-time.After(time.Second):
+		t.Fatalf("Crashed game didn't count as gone")
+	}
+	<-r.done()
+	if r.Err() == nil {
+		t.Errorf("Crashed game has no error")
+	}
+}
+
+func TestGameRunnerGone(t *testing.T) {
+	defer func(timeout time.Duration) { disconnectTimeout = timeout }(disconnectTimeout)
+	disconnectTimeout = 100 * time.Millisecond
+
+	cases := []struct {
+		name string
+		poll bool
+		gone bool
+	}{
+		{"Nobody asking", false, true},
+		{"Still asking", true, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			gone := make(chan struct{})
+			r := NewGameRunner(ctx, tetris.NewGame(boardHeight, boardWidth, 0, tetris.DefaultRules()), time.Now(), nil, func() { close(gone) })
+
+			deadline := time.After(3 * disconnectTimeout)
+			for waiting := true; waiting; {
+				select {
+				case <-gone:
+					waiting = false
+				case <-deadline:
+					waiting = false
+				case <-time.After(disconnectTimeout / 4):
+					if c.poll {
+						r.Snapshot()
+					}
+				}
+			}
+
+			if isGone := isClosed(gone); isGone != c.gone {
+				t.Errorf("Expected the player to be gone: %v, got %v", c.gone, isGone)
+			}
+			if isClosed(r.done()) != c.gone {
+				t.Errorf("Expected the runner to be stopped: %v", c.gone)
+			}
+		})
+	}
+}
+
+func TestGameRunnerPacing(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	start := time.Now().Add(100 * time.Millisecond)
+	r := NewGameRunner(ctx, tetris.NewGame(boardHeight, boardWidth, 0, tetris.DefaultRules()), start, nil, nil)
+
+	// Nothing happens before the start
+	r.Act(tetris.ActionDrop)
+	time.Sleep(50 * time.Millisecond)
+	if g, _ := r.Snapshot(); g.Frames() != 0 || g.Placed() != 0 {
+		t.Fatalf("Game ran before the start, %v frames in", g.Frames())
+	}
+
+	// Frames follow the wall clock, and inputs go in on the next step
+	time.Sleep(time.Until(start) + 500*time.Millisecond)
+	g, events := r.Snapshot()
+	if frames := g.Frames(); frames < 20 || frames > 35 {
+		t.Errorf("Expected about 30 frames in half a second, got %v", frames)
+	}
+	if g.Placed() != 1 || !hasEvent[tetris.PieceLocked](events) {
+		t.Errorf("The drop didn't happen")
+	}
+	if _, events := r.Snapshot(); len(events) > 0 && hasEvent[tetris.PieceLocked](events) {
+		t.Errorf("Got the same events twice")
+	}
+
+	r.Stop()
+	<-r.done()
+}
+
+func isClosed(c <-chan struct{}) bool {
+	select {
+	case <-c:
+		return true
+	default:
+		return false
+	}
+}
+
+func hasEvent[E tetris.Event](events []tetris.Event) bool {
+	for _, e := range events {
+		if _, ok := e.(E); ok {
+			return true
+		}
+	}
+	return false
+}
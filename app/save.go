@@ -0,0 +1,117 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"tetrissh/tetris"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/log"
+)
+
+// Where unfinished single player games are kept, one per player, so they can be continued later
+var SaveDir = "saves"
+
+// How often a running game is saved. A dropped connection loses at most this much of a game
+const saveInterval = 5 * time.Second
+
+func savePath(player string) string {
+	return filepath.Join(SaveDir, player+".json")
+}
+
+func saveGame(player string, g *tetris.Game) error {
+	data, err := json.Marshal(g)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(SaveDir, 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(savePath(player), data, 0o644)
+}
+
+func loadGame(player string) (tetris.Game, error) {
+	var g tetris.Game
+
+	data, err := os.ReadFile(savePath(player))
+	if err != nil {
+		return g, err
+	}
+
+	err = json.Unmarshal(data, &g)
+	return g, err
+}
+
+func deleteSave(player string) error {
+	if err := os.Remove(savePath(player)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func hasSave(player string) bool {
+	if player == "" {
+		return false
+	}
+	_, err := os.Stat(savePath(player))
+	return err == nil
+}
+
+// Saves one game for a player, writing in the background so a long game's save doesn't hold up
+// the UI. Writes happen one at a time, and once the game is done with, saves still on their way
+// are dropped so they can't bring back a save that was just deleted or written over
+type gameSaver struct {
+	mx     sync.Mutex
+	player string
+	frames int // Of the last game written, so an older one that's late doesn't overwrite it
+	done   bool
+}
+
+func newGameSaver(player string) *gameSaver {
+	return &gameSaver{player: player}
+}
+
+// Save g in the background
+func (s *gameSaver) save(g tetris.Game) tea.Cmd {
+	return func() tea.Msg {
+		s.mx.Lock()
+		defer s.mx.Unlock()
+		if s.done || g.Frames() < s.frames {
+			return nil
+		}
+		s.write(&g)
+		return nil
+	}
+}
+
+// Save g one last time, right now
+func (s *gameSaver) close(g *tetris.Game) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	if !s.done {
+		s.write(g)
+		s.done = true
+	}
+}
+
+// Delete the save right now, and don't save anything after
+func (s *gameSaver) delete() error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	s.done = true
+	return deleteSave(s.player)
+}
+
+func (s *gameSaver) write(g *tetris.Game) {
+	if err := saveGame(s.player, g); err != nil {
+		log.Error("Couldn't save game", "error", err)
+		return
+	}
+	s.frames = g.Frames()
+}
@@ -0,0 +1,62 @@
+package app
+
+import (
+	"testing"
+	"tetrissh/tetris"
+)
+
+func TestGameSaver(t *testing.T) {
+	defer func(dir string) { SaveDir = dir }(SaveDir)
+
+	at := func(frames int) tetris.Game {
+		g := tetris.NewGame(boardHeight, boardWidth, 0, tetris.DefaultRules())
+		g.Step(frames, nil)
+		return g
+	}
+
+	// Each step is a save in the background, or with close/delete the game being done with
+	cases := []struct {
+		name   string
+		run    func(s *gameSaver)
+		frames int // Of the save left behind, -1 for none
+	}{
+		{"Saves", func(s *gameSaver) { s.save(at(10))() }, 10},
+		{"Late save doesn't go back", func(s *gameSaver) {
+			old := s.save(at(10))
+			s.save(at(20))()
+			old()
+		}, 20},
+		{"Nothing after closing", func(s *gameSaver) {
+			late := s.save(at(30))
+			g := at(20)
+			s.close(&g)
+			late()
+		}, 20},
+		{"Nothing after deleting", func(s *gameSaver) {
+			late := s.save(at(10))
+			s.delete()
+			late()
+		}, -1},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			SaveDir = t.TempDir()
+			c.run(newGameSaver("player"))
+
+			if !hasSave("player") {
+				if c.frames != -1 {
+					t.Fatalf("Expected a save")
+				}
+				return
+			}
+			g, err := loadGame("player")
+			if err != nil {
+				t.Fatalf("Couldn't load the save: %v", err)
+			}
+			if g.Frames() != c.frames {
+				t.Errorf("Expected the save from frame %v, got %v", c.frames, g.Frames())
+			}
+		})
+	}
+}
@@ -9,11 +9,20 @@ import (
 )
 
 type MultiplayerSession struct {
-	ctx   context.Context
-	board *[][]int
-	score *int
-	mx    *sync.RWMutex
-	err   error
+	ctx      context.Context
+	board    *[][]int
+	score    *int
+	stats    MatchStats
+	incoming []int // Garbage the opponent has sent that hasn't been queued in our game yet
+	mx       *sync.RWMutex
+	err      error
+}
+
+func NewMultiplayerSession(ctx context.Context) *MultiplayerSession {
+	return &MultiplayerSession{
+		ctx: ctx,
+		mx:  new(sync.RWMutex),
+	}
 }
 
 func (m *MultiplayerSession) done() <-chan struct{} {
@@ -46,6 +55,11 @@ func (m *MultiplayerSession) Board() (board [][]int) {
 	return board
 }
 
+// Opponents don't share where their piece is going to land
+func (m *MultiplayerSession) Ghost() [][]int {
+	return nil
+}
+
 // Thread safe setter. Blocks for mutex.
 // TODO: Should error when trying to do this on a canceled session
 func (m *MultiplayerSession) SetBoard(b [][]int) {
@@ -67,58 +81,42 @@ func (m *MultiplayerSession) Score() int {
 	return 0
 }
 
-// func (m *MultiplayerSession) SetScore() int {
-// }
-
-/*** MATCHMAKING ***/
+// Thread safe setter, score included. Blocks for mutex.
+func (m *MultiplayerSession) SetStats(stats MatchStats) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
 
-type matchReq struct {
-	session *MultiplayerSession
-	opC     chan<- *MultiplayerSession
+	m.stats = stats
+	m.score = &stats.Score
 }
 
-var matchReqC = make(chan matchReq)
-
-// Request a match and return a recieving channel that the opponent's session will be returned through
-func (s *MultiplayerSession) requestMatch() <-chan *MultiplayerSession {
-	opC := make(chan *MultiplayerSession, 1) // Don't want to block matchmaking when sending
+// THREAD SAFE.
+func (m *MultiplayerSession) Stats() MatchStats {
+	m.mx.RLock()
+	defer m.mx.RUnlock()
 
-	matchReqC <- matchReq{
-		session: s,
-		opC:     opC,
-	}
+	return m.stats
+}
 
-	return opC
+// Whatever went wrong the last time the board was read
+func (m *MultiplayerSession) Err() error {
+	return m.err
 }
 
-// On a loop, match requests. Meant to be used in a goroutine in main
-func MatchMultiplayerGames() {
-	var lastReq *matchReq
+// Called by the opponent to attack this session's player. Thread safe, blocks for mutex
+func (m *MultiplayerSession) SendGarbage(lines int) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
 
-	for {
-		nextReq := <-matchReqC
+	m.incoming = append(m.incoming, lines)
+}
 
-		if lastReq == nil {
-			lastReq = &nextReq
-		} else {
-			select {
-			case <-lastReq.session.done(): // Has the last request been canceled?
-				log.Debug("match request canceled")
-				close(lastReq.opC)
-				*lastReq = nextReq
-			case <-nextReq.session.done():
-				close(nextReq.opC)
-				continue // Skip this request if context is canceled
-			default:
-				log.Debug("Exchanging match requests")
-				// the sessions have a <-chan, so we don't have to worry about them already being filled here
-				lastReq.opC <- nextReq.session
-				close(lastReq.opC)
-				nextReq.opC <- lastReq.session
-				close(nextReq.opC)
-
-				lastReq = nil
-			}
-		}
-	}
+// Everything the opponent has sent since the last call. Thread safe, blocks for mutex
+func (m *MultiplayerSession) takeGarbage() []int {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	incoming := m.incoming
+	m.incoming = nil
+	return incoming
 }
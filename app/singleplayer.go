@@ -1,25 +1,99 @@
 package app
 
 import (
+	"context"
 	"fmt"
+	"math/rand/v2"
+	"tetrissh/tetris"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/log"
 )
 
 type SinglePlayer struct {
-	gm *GameModel
+	gm          *GameModel
+	mode        *tetris.Mode // nil for games that aren't one of the standard modes
+	player      string       // Who to save the game for, empty for players we can't identify
+	saver       *gameSaver
+	lastSave    time.Time
+	replaySaved bool
+	ended       bool // Zen never ends on its own, so the player ends it
 }
 
-func NewSinglePlayer() SinglePlayer {
-	gm := NewGameModel()
+// The game stops when ctx is canceled, like when the player disconnects
+func NewSinglePlayer(ctx context.Context, player string) SinglePlayer {
+	return newSinglePlayer(ctx, tetris.NewGame(boardHeight, boardWidth, rand.Uint64(), tetris.DefaultRules()), player)
+}
+
+// Start a game of one of the standard modes
+func NewModeSinglePlayer(ctx context.Context, mode tetris.Mode, player string) SinglePlayer {
+	return newSinglePlayer(ctx, tetris.NewGame(boardHeight, boardWidth, rand.Uint64(), mode.Rules), player)
+}
+
+// Play an already set up game
+func newSinglePlayer(ctx context.Context, g tetris.Game, player string) SinglePlayer {
+	gm := runGameModel(ctx, g)
+	s := SinglePlayer{
+		gm:     &gm,
+		player: player,
+		saver:  newGameSaver(player),
+	}
+	if mode, ok := tetris.ModeOf(g.Rules()); ok {
+		s.mode = &mode
+	}
+	return s
+}
 
-	return SinglePlayer{
-		gm: &gm,
+func (s SinglePlayer) modeName() string {
+	if s.mode == nil {
+		return ""
 	}
+	return s.mode.Name
+}
+
+// Pick up the player's saved game
+func ContinueSinglePlayer(ctx context.Context, player string) (SinglePlayer, error) {
+	g, err := loadGame(player)
+	if err != nil {
+		return SinglePlayer{}, err
+	}
+
+	return newSinglePlayer(ctx, g, player), nil
 }
 
 func (s SinglePlayer) Init() tea.Cmd {
-	return FallTickCmd()
+	return s.gm.Init()
+}
+
+// Whether there's a game to save so it can be continued, and a player to save it for
+func (s SinglePlayer) canSave() bool {
+	return s.player != "" && !s.gm.GameOver && !s.ended
+}
+
+// Save the game in the background, every so often while it's played
+func (s *SinglePlayer) save() tea.Cmd {
+	if !s.canSave() {
+		return nil
+	}
+	s.lastSave = time.Now()
+	return s.saver.save(s.gm.Clone())
+}
+
+// Save the replay of a game that's over, and drop its save since there's nothing left to continue
+func (s *SinglePlayer) finish() {
+	if s.replaySaved {
+		return
+	}
+
+	if err := saveReplay(s.gm.Replay()); err != nil {
+		log.Error("Couldn't save replay", "error", err)
+	}
+	if err := s.saver.delete(); err != nil {
+		log.Error("Couldn't delete finished game's save", "error", err)
+	}
+	s.replaySaved = true
 }
 
 func (s SinglePlayer) Update(msg tea.Msg) (m tea.Model, cmd tea.Cmd) {
@@ -27,18 +101,77 @@ func (s SinglePlayer) Update(msg tea.Msg) (m tea.Model, cmd tea.Cmd) {
 	case tea.KeyMsg:
 		switch msg.String() {
 		case "q", "ctrl+c":
+			// The first q ends a Zen session and shows how it went
+			if s.modeName() == tetris.ModeZen.Name && !s.ended && !s.gm.GameOver {
+				s.ended = true
+				s.gm.stop()
+				s.finish()
+				return s, nil
+			}
+			s.gm.stop()
+			if s.canSave() {
+				s.saver.close(s.gm.Game)
+			}
 			return s, DeactivateCmd
 		}
 	}
+	if s.ended {
+		return s, nil
+	}
 
 	*s.gm, cmd = s.gm.Update(msg)
+
+	if s.gm.GameOver {
+		s.finish()
+	} else if time.Since(s.lastSave) >= saveInterval {
+		cmd = tea.Batch(cmd, s.save())
+	}
 	return s, cmd
 }
 
 func (s SinglePlayer) View() string {
-	if s.gm.GameOver {
-		return fmt.Sprintf("Your final score is %v! Press q to go back to menu", s.gm.Score())
-	} else {
+	if s.gm.GameOver || s.ended {
+		return s.endView() + "\nPress q to go back to menu"
+	}
+	if s.mode == nil {
 		return s.gm.View()
 	}
+	return lipgloss.JoinVertical(lipgloss.Center, s.timerView(), s.gm.View())
+}
+
+// The mode and its clock. Ultra counts down, everything else counts up
+func (s SinglePlayer) timerView() string {
+	frames := s.gm.Frames()
+	if limit := s.mode.Rules.Goal.Frames; limit > 0 {
+		frames = max(limit-frames, 0)
+	}
+	return fmt.Sprintf("%v %v", s.mode.Name, formatFrames(frames))
+}
+
+// How the game went, which depends on what the mode was about
+func (s SinglePlayer) endView() string {
+	g := s.gm
+	won := g.Outcome() == tetris.OutcomeWon
+
+	switch s.modeName() {
+	case tetris.ModeSprint.Name:
+		if won {
+			return fmt.Sprintf("Sprint finished in %v!", formatFrames(g.Frames()))
+		}
+		return fmt.Sprintf("Topped out with %v/%v lines", g.Lines(), s.mode.Rules.Goal.Lines)
+	case tetris.ModeUltra.Name:
+		if won {
+			return fmt.Sprintf("Time's up! You scored %v with %v lines", g.Score(), g.Lines())
+		}
+		return fmt.Sprintf("Topped out at %v with a score of %v", formatFrames(g.Frames()), g.Score())
+	case tetris.ModeMarathon.Name:
+		if won {
+			return fmt.Sprintf("Marathon complete! You scored %v in %v", g.Score(), formatFrames(g.Frames()))
+		}
+		return fmt.Sprintf("Topped out on level %v with %v lines and a score of %v", g.Level(), g.Lines(), g.Score())
+	case tetris.ModeZen.Name:
+		return fmt.Sprintf("You cleared %v lines in %v, for a score of %v", g.Lines(), formatFrames(g.Frames()), g.Score())
+	default:
+		return fmt.Sprintf("Your final score is %v!", g.Score())
+	}
 }
@@ -0,0 +1,194 @@
+// Package bot plays tetris. It finds every place the active piece can end up and picks the best one
+// with a Dellacherie style heuristic, for CPU opponents, hints and testing the engine
+package bot
+
+import (
+	"fmt"
+	"slices"
+	"tetrissh/tetris"
+)
+
+// Somewhere the active piece (or the piece swapped in by holding) can lock
+type Placement struct {
+	// Everything to press, in order, ending with a hard drop
+	Actions []tetris.Action
+	Hold    bool // Do the Actions start with a hold?
+	Piece   tetris.Piece
+	// Blocks the piece ends up on, as {x, y} with y down like the board
+	Blocks [][2]int
+	// The stack once the piece has locked and any lines have cleared
+	Board  [][]int
+	Lines  int
+	eroded int // Blocks of the piece that went with the cleared lines
+}
+
+type Bot struct {
+	Weights Weights
+}
+
+func New(w Weights) Bot {
+	return Bot{Weights: w}
+}
+
+// The best placement for the game's active piece, and false if there isn't an active piece
+func (b Bot) Best(g tetris.Game) (Placement, bool) {
+	var best Placement
+	bestScore := 0.0
+	found := false
+
+	for _, p := range Placements(g) {
+		score := b.Weights.Score(p)
+		if !found || score > bestScore {
+			best, bestScore, found = p, score, true
+		}
+	}
+	return best, found
+}
+
+// Every distinct place the active piece can lock, by moving, turning and soft dropping it,
+// and the same for the piece holding would bring in. Each comes with the fewest actions that get there
+func Placements(g tetris.Game) []Placement {
+	if _, ok := g.Active(); !ok {
+		return nil
+	}
+
+	placements := search(g.Clone(), nil)
+
+	if g.CanHold() {
+		held := g.Clone()
+		held.Act(tetris.ActionHold)
+		if !held.GameOver {
+			placements = append(placements, search(held, []tetris.Action{tetris.ActionHold})...)
+		}
+	}
+	return placements
+}
+
+// Everything the game can do with the active piece before it locks
+var moves = []tetris.Action{
+	tetris.ActionLeft,
+	tetris.ActionRight,
+	tetris.ActionRotate,
+	tetris.ActionRotateCCW,
+	tetris.ActionDown,
+}
+
+type node struct {
+	g       tetris.Game
+	actions []tetris.Action
+}
+
+// Breadth first over every position the piece can be moved to, so the first way found to reach
+// a placement is the shortest
+func search(g tetris.Game, prefix []tetris.Action) []Placement {
+	var placements []Placement
+	seen := map[state]bool{}
+	landed := map[string]bool{}
+
+	start, ok := g.Active()
+	if !ok {
+		return nil
+	}
+	seen[key(start)] = true
+	queue := []node{{g: g, actions: prefix}}
+
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+
+		if blocks := landing(n.g); !landed[blocksKey(blocks)] {
+			landed[blocksKey(blocks)] = true
+			placements = append(placements, placement(n, blocks))
+		}
+
+		for _, a := range moves {
+			next := n.g.Clone()
+			next.Act(a)
+			active, ok := next.Active()
+			if !ok || seen[key(active)] {
+				continue
+			}
+			seen[key(active)] = true
+
+			actions := make([]tetris.Action, len(n.actions), len(n.actions)+1)
+			copy(actions, n.actions)
+			queue = append(queue, node{g: next, actions: append(actions, a)})
+		}
+	}
+	return placements
+}
+
+// Where the piece is. Every node in a search has the same piece, so that's left out
+type state struct {
+	rot  tetris.Rotation
+	x, y int
+}
+
+func key(a tetris.ActivePiece) state {
+	return state{rot: a.Rotation, x: a.X, y: a.Y}
+}
+
+// Blocks the active piece would land on if it was dropped now
+func landing(g tetris.Game) [][2]int {
+	var blocks [][2]int
+	for y, row := range g.Ghost() {
+		for x, c := range row {
+			if c > 0 {
+				blocks = append(blocks, [2]int{x, y})
+			}
+		}
+	}
+	return blocks
+}
+
+// Dropping the node's piece onto blocks
+func placement(n node, blocks [][2]int) Placement {
+	active, _ := n.g.Active()
+
+	board := n.g.Stack()
+	for _, b := range blocks {
+		board[b[1]][b[0]] = int(active.Piece.Color())
+	}
+	board, lines := clearLines(board)
+	eroded := 0
+	for _, b := range blocks {
+		if slices.Contains(lines, b[1]) {
+			eroded++
+		}
+	}
+
+	actions := make([]tetris.Action, len(n.actions), len(n.actions)+1)
+	copy(actions, n.actions)
+	return Placement{
+		Actions: append(actions, tetris.ActionDrop),
+		Hold:    len(n.actions) > 0 && n.actions[0] == tetris.ActionHold,
+		Piece:   active.Piece,
+		Blocks:  blocks,
+		Board:   board,
+		Lines:   len(lines),
+		eroded:  eroded,
+	}
+}
+
+func blocksKey(blocks [][2]int) string {
+	return fmt.Sprint(blocks)
+}
+
+// Take out full rows, dropping everything above them. Returns the rows that were full
+func clearLines(board [][]int) ([][]int, []int) {
+	var full []int
+	kept := make([][]int, 0, len(board))
+	for y, row := range board {
+		if slices.Contains(row, 0) {
+			kept = append(kept, row)
+		} else {
+			full = append(full, y)
+		}
+	}
+	if len(full) == 0 {
+		return board, nil
+	}
+
+	cleared := tetris.NewBoard(len(full), len(board[0]))
+	return append(cleared, kept...), full
+}
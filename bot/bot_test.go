@@ -0,0 +1,99 @@
+package bot
+
+import (
+	"reflect"
+	"testing"
+	"tetrissh/tetris"
+)
+
+func newGame(t *testing.T, rows []string, seq ...string) tetris.Game {
+	t.Helper()
+	board, err := tetris.ParseBoard(20, 10, rows...)
+	if err != nil {
+		t.Fatalf("Couldn't parse board: %v", err)
+	}
+	g, err := tetris.NewGameFrom(20, 10, 0, tetris.DefaultRules(), tetris.Setup{Board: board, Sequence: seq, Only: true})
+	if err != nil {
+		t.Fatalf("Couldn't set up game: %v", err)
+	}
+	return g
+}
+
+func TestPlacements(t *testing.T) {
+	cases := []struct {
+		name     string
+		seq      []string
+		expected int
+	}{
+		// 9 columns for the O, then the I held in: 7 flat and 10 standing
+		{"O then I", []string{"O", "I"}, 9 + 17},
+		// Holding the T brings in another T, which can't be held again
+		{"T then T", []string{"T", "T"}, 34 + 34},
+		{"Nothing to hold", []string{"T"}, 34},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			g := newGame(t, nil, c.seq...)
+			if n := len(Placements(g)); n != c.expected {
+				t.Errorf("Expected %v placements, got %v", c.expected, n)
+			}
+		})
+	}
+}
+
+// Every placement's actions have to put the piece where the placement says
+func TestPlacementActions(t *testing.T) {
+	g := tetris.NewGame(20, 10, 7, tetris.DefaultRules())
+	b := New(DefaultWeights())
+	for i := 0; i < 20; i++ {
+		p, _ := b.Best(g)
+		g.Step(0, p.Actions)
+	}
+
+	for _, p := range Placements(g) {
+		played := g.Clone()
+		played.Step(0, p.Actions)
+		if !reflect.DeepEqual(played.Stack(), p.Board) {
+			t.Errorf("Actions %v for %v didn't end up where expected", p.Actions, p.Piece.Name())
+		}
+	}
+}
+
+func TestTuck(t *testing.T) {
+	// Anywhere but under the overhang on the left leaves holes
+	g := newGame(t, []string{
+		"##........",
+		"....######",
+		"....######",
+	}, "O")
+
+	p, _ := New(DefaultWeights()).Best(g)
+	expected := [][2]int{{0, 18}, {1, 18}, {0, 19}, {1, 19}}
+	if !reflect.DeepEqual(p.Blocks, expected) {
+		t.Fatalf("Expected the O to be tucked under the overhang, it went to %v", p.Blocks)
+	}
+
+	played := g.Clone()
+	played.Step(0, p.Actions)
+	if !reflect.DeepEqual(played.Stack(), p.Board) {
+		t.Errorf("Actions %v didn't tuck the O in", p.Actions)
+	}
+}
+
+func TestBotPlays(t *testing.T) {
+	g := tetris.NewGame(20, 10, 1, tetris.DefaultRules())
+	b := New(DefaultWeights())
+
+	for i := 0; i < 200 && !g.GameOver; i++ {
+		p, ok := b.Best(g)
+		if !ok {
+			t.Fatalf("No placement for piece %v", i)
+		}
+		g.Step(0, p.Actions)
+	}
+
+	if g.GameOver || g.Lines() < 60 {
+		t.Errorf("Bot should last 200 pieces and clear plenty of lines, cleared %v (game over: %v)", g.Lines(), g.GameOver)
+	}
+}
@@ -0,0 +1,121 @@
+package bot
+
+// How much each feature of a placement counts, Pierre Dellacherie's features. Most are bad,
+// so most weights are negative
+type Weights struct {
+	LandingHeight     float64 // Height of the middle of the piece after it lands
+	ErodedCells       float64 // Lines cleared times the piece's blocks in them
+	RowTransitions    float64 // Changes between filled and empty along each row, walls counting as filled
+	ColumnTransitions float64 // Same down each column, the floor counting as filled
+	Holes             float64 // Empty cells with something above them
+	Wells             float64 // Each well counts 1 + 2 + ... + its depth
+}
+
+// The weights El-Tetris tuned for Dellacherie's features
+func DefaultWeights() Weights {
+	return Weights{
+		LandingHeight:     -4.500158825082766,
+		ErodedCells:       3.4181268101392694,
+		RowTransitions:    -3.2178882868487753,
+		ColumnTransitions: -9.348695305445199,
+		Holes:             -7.899265427351652,
+		Wells:             -3.3855972247263626,
+	}
+}
+
+// Dellacherie's own hand picked weights
+func DellacherieWeights() Weights {
+	return Weights{
+		LandingHeight:     -1,
+		ErodedCells:       1,
+		RowTransitions:    -1,
+		ColumnTransitions: -1,
+		Holes:             -4,
+		Wells:             -1,
+	}
+}
+
+// How good a placement is, higher being better
+func (w Weights) Score(p Placement) float64 {
+	return w.LandingHeight*landingHeight(p) +
+		w.ErodedCells*float64(p.Lines*p.eroded) +
+		w.RowTransitions*float64(rowTransitions(p.Board)) +
+		w.ColumnTransitions*float64(columnTransitions(p.Board)) +
+		w.Holes*float64(holes(p.Board)) +
+		w.Wells*float64(wells(p.Board))
+}
+
+func landingHeight(p Placement) float64 {
+	if len(p.Blocks) == 0 {
+		return 0
+	}
+
+	top, bottom := p.Blocks[0][1], p.Blocks[0][1]
+	for _, b := range p.Blocks {
+		top, bottom = min(top, b[1]), max(bottom, b[1])
+	}
+	// Rows count up from the floor, the bottom row being 1
+	return float64(len(p.Board)) - float64(top+bottom)/2
+}
+
+func filled(board [][]int, x, y int) bool {
+	if x < 0 || x >= len(board[0]) || y >= len(board) {
+		return true
+	}
+	return y >= 0 && board[y][x] > 0
+}
+
+func rowTransitions(board [][]int) int {
+	n := 0
+	for y := range board {
+		for x := 0; x <= len(board[y]); x++ {
+			if filled(board, x-1, y) != filled(board, x, y) {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+func columnTransitions(board [][]int) int {
+	n := 0
+	for x := range board[0] {
+		for y := 0; y <= len(board); y++ {
+			if filled(board, x, y-1) != filled(board, x, y) {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+func holes(board [][]int) int {
+	n := 0
+	for x := range board[0] {
+		covered := false
+		for y := range board {
+			if board[y][x] > 0 {
+				covered = true
+			} else if covered {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+func wells(board [][]int) int {
+	n := 0
+	for x := range board[0] {
+		depth := 0
+		for y := range board {
+			if !filled(board, x, y) && filled(board, x-1, y) && filled(board, x+1, y) {
+				depth++
+				n += depth
+			} else {
+				depth = 0
+			}
+		}
+	}
+	return n
+}
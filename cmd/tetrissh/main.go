@@ -2,7 +2,10 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"flag"
 	"net"
 	"os"
 	"os/signal"
@@ -17,6 +20,7 @@ import (
 	"github.com/charmbracelet/wish/activeterm"
 	"github.com/charmbracelet/wish/bubbletea"
 	"github.com/charmbracelet/wish/logging"
+	gossh "golang.org/x/crypto/ssh"
 )
 
 const (
@@ -24,12 +28,22 @@ const (
 	port = "42069"
 )
 
-var newsessions = make(chan *app.MultiplayerSession)
+// Canceled when the server starts shutting down, ending every match still going
+var serverCtx, stopServer = context.WithCancel(context.Background())
 
 func main() {
+	flag.StringVar(&app.PuzzleDir, "puzzles", app.PuzzleDir, "directory to load puzzle files from")
+	flag.StringVar(&app.PieceSetDir, "pieces", app.PieceSetDir, "directory to load extra piece sets from")
+	flag.Parse()
+
+	app.LoadPieceSets()
+
 	s, err := wish.NewServer(
 		wish.WithAddress(net.JoinHostPort(host, port)),
 		wish.WithHostKeyPath(".ssh/id_ed25519"),
+		// Let anyone in, but take a public key if they have one so their games can be saved
+		wish.WithPublicKeyAuth(publicKeyAuth),
+		wish.WithKeyboardInteractiveAuth(keyboardInteractiveAuth),
 		wish.WithMiddleware(
 			bubbletea.Middleware(teaHandler),
 			activeterm.Middleware(), // Bubble Tea apps usually require a PTY.
@@ -41,8 +55,7 @@ func main() {
 	}
 
 	// Only invoke once!
-	// TODO: hook into done channel
-	go app.MatchMultiplayerGames()
+	go app.Matchmaking.Run(serverCtx)
 
 	done := make(chan os.Signal, 1)
 	signal.Notify(done, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
@@ -56,6 +69,7 @@ func main() {
 
 	<-done
 	log.Info("Stopping SSH server")
+	stopServer()
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 	if err := s.Shutdown(ctx); err != nil && !errors.Is(err, ssh.ErrServerClosed) {
@@ -70,6 +84,43 @@ func main() {
 func teaHandler(s ssh.Session) (tea.Model, []tea.ProgramOption) {
 	renderer := bubbletea.MakeRenderer(s)
 
-	m := app.NewAppModel(renderer)
+	// The session's context is canceled when the connection closes, and we cancel it ourselves
+	// when the server's going down
+	ctx, cancel := context.WithCancel(s.Context())
+	stop := context.AfterFunc(serverCtx, cancel)
+	context.AfterFunc(ctx, func() { stop() })
+
+	m := app.NewAppModel(ctx, renderer, playerID(s.Context()))
 	return m, []tea.ProgramOption{tea.WithAltScreen()}
 }
+
+// Set when a connection offers more than one key
+var keyAmbiguous = &struct{ name string }{"key-ambiguous"}
+
+// Lets every key in. The ssh package remembers the last key offered, but that's not necessarily
+// the one the client signed with: offering a key doesn't prove you own it, and x/crypto skips this
+// for keys it's already seen. So once a second key shows up we don't trust any of them
+func publicKeyAuth(ctx ssh.Context, key ssh.PublicKey) bool {
+	if prev, ok := ctx.Value(ssh.ContextKeyPublicKey).(ssh.PublicKey); ok && !ssh.KeysEqual(prev, key) {
+		ctx.SetValue(keyAmbiguous, true)
+	}
+	return true
+}
+
+// Lets everyone in, forgetting any key they offered without signing for it
+func keyboardInteractiveAuth(ctx ssh.Context, challenger gossh.KeyboardInteractiveChallenge) bool {
+	ctx.SetValue(ssh.ContextKeyPublicKey, nil)
+	return true
+}
+
+// Hash of the public key the player logged in with, to recognize them across sessions.
+// Empty if they didn't log in with one
+func playerID(ctx ssh.Context) string {
+	key, ok := ctx.Value(ssh.ContextKeyPublicKey).(ssh.PublicKey)
+	if !ok || ctx.Value(keyAmbiguous) != nil {
+		return ""
+	}
+
+	sum := sha256.Sum256(key.Marshal())
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"testing"
+
+	"github.com/charmbracelet/ssh"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// Just enough of an ssh.Context to hold the values auth sets
+type testContext struct {
+	ssh.Context
+	values map[any]any
+}
+
+func (c *testContext) Value(key any) any       { return c.values[key] }
+func (c *testContext) SetValue(key, value any) { c.values[key] = value }
+
+func testKey(t *testing.T) ssh.PublicKey {
+	pub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("Couldn't generate a key: %v", err)
+	}
+	key, err := gossh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatalf("Couldn't convert key: %v", err)
+	}
+	return key
+}
+
+func TestPlayerID(t *testing.T) {
+	ours, theirs := testKey(t), testKey(t)
+
+	// Each step is a login attempt: a public key, or keyboard-interactive when nil
+	cases := []struct {
+		name  string
+		steps []ssh.PublicKey
+		id    bool
+	}{
+		{"Public key", []ssh.PublicKey{ours}, true},
+		{"Same key twice", []ssh.PublicKey{ours, ours}, true},
+		{"Keyboard-interactive", []ssh.PublicKey{nil}, false},
+		{"Offered a key then keyboard-interactive", []ssh.PublicKey{theirs, nil}, false},
+		{"Offered two keys", []ssh.PublicKey{ours, theirs, ours}, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ctx := &testContext{values: map[any]any{}}
+			for _, key := range c.steps {
+				if key == nil {
+					keyboardInteractiveAuth(ctx, nil)
+					continue
+				}
+				// Like the ssh package does when the handler lets the key in
+				if publicKeyAuth(ctx, key) {
+					ctx.SetValue(ssh.ContextKeyPublicKey, key)
+				}
+			}
+
+			if id := playerID(ctx); (id != "") != c.id {
+				t.Errorf("Got player id %q, expected one: %v", id, c.id)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package tetris
+
+// Garbage lines sent for line clears in versus, from the guideline's attack table
+var (
+	// Indexed by lines cleared
+	attackLines = []int{0, 0, 1, 2, 4}
+	attackMini  = []int{0, 0, 1}
+	attackSpin  = []int{0, 2, 4, 6}
+	// Indexed by the combo, so the first clear of a combo gets nothing extra
+	attackCombo = []int{0, 0, 1, 1, 2, 2, 3, 3, 4, 4, 4, 5}
+)
+
+const (
+	attackB2B          = 1
+	attackPerfectClear = 10
+)
+
+// Lines of garbage the clear sends to an opponent
+func (c Clear) Attack() int {
+	if c.Lines() == 0 {
+		return 0
+	}
+
+	var lines int
+	switch c.Spin {
+	case SpinMini:
+		lines = tableScore(attackMini, c.Lines())
+	case SpinFull:
+		lines = tableScore(attackSpin, c.Lines())
+	default:
+		lines = tableScore(attackLines, c.Lines())
+	}
+
+	if c.BackToBack {
+		lines += attackB2B
+	}
+	if c.Combo > 0 {
+		lines += tableScore(attackCombo, c.Combo)
+	}
+	if c.PerfectClear {
+		lines += attackPerfectClear
+	}
+	return lines
+}
+
+// Use the clear's attack to cancel queued garbage, oldest first, and send whatever's left over
+func (g *Game) attack(c Clear) {
+	lines := c.Attack()
+	if lines == 0 {
+		return
+	}
+
+	// Clones share the queue, so it's rebuilt instead of changed in place
+	var left []Garbage
+	canceled := 0
+	for _, gb := range g.garbage {
+		n := min(lines, gb.Lines)
+		gb.Lines -= n
+		lines -= n
+		canceled += n
+		if gb.Lines > 0 {
+			left = append(left, gb)
+		}
+	}
+	g.garbage = left
+
+	g.emit(Attack{Lines: lines, Canceled: canceled})
+}
@@ -0,0 +1,94 @@
+package tetris
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestAttack(t *testing.T) {
+	cases := []struct {
+		name   string
+		clear  Clear
+		attack int
+	}{
+		{"Nothing", Clear{Combo: -1}, 0},
+		{"Single", Clear{Rows: make([]int, 1)}, 0},
+		{"Double", Clear{Rows: make([]int, 2)}, 1},
+		{"Tetris", Clear{Rows: make([]int, 4)}, 4},
+		{"Back to back tetris", Clear{Rows: make([]int, 4), BackToBack: true}, 5},
+		{"Mini T-spin double", Clear{Rows: make([]int, 2), Spin: SpinMini}, 1},
+		{"T-spin double", Clear{Rows: make([]int, 2), Spin: SpinFull}, 4},
+		{"T-spin triple", Clear{Rows: make([]int, 3), Spin: SpinFull}, 6},
+		{"T-spin with nothing cleared", Clear{Spin: SpinFull, Combo: -1}, 0},
+		{"Combo", Clear{Rows: make([]int, 1), Combo: 2}, 1},
+		{"Long combo", Clear{Rows: make([]int, 2), Combo: 20}, 6},
+		{"Perfect clear", Clear{Rows: make([]int, 2), PerfectClear: true}, 11},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if a := c.clear.Attack(); a != c.attack {
+				t.Errorf("Expected an attack of %v, got %v", c.attack, a)
+			}
+		})
+	}
+}
+
+func TestAttackCancelsGarbage(t *testing.T) {
+	cases := []struct {
+		name     string
+		queued   []int
+		attack   Attack
+		pending  int
+		received bool
+	}{
+		{"Nothing queued", nil, Attack{Lines: 4}, 0, false},
+		{"Some canceled", []int{1, 2}, Attack{Lines: 1, Canceled: 3}, 0, false},
+		{"All canceled", []int{3, 2}, Attack{Canceled: 4}, 1, false},
+	}
+
+	// A tetris ready for an I, without a perfect clear
+	board, err := ParseBoard(20, 10,
+		"#########.",
+		"#########.",
+		"#########.",
+		"#########.",
+		"########.#",
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			g, err := NewGameFrom(20, 10, 0, DefaultRules(), Setup{Board: board, Sequence: []string{"I", "O"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, lines := range c.queued {
+				g.QueueGarbage(lines, 0)
+			}
+			g.Events()
+
+			// Stand the I up and take it all the way to the right
+			events := g.Step(0, slices.Concat([]Action{ActionRotate}, times(6, ActionRight), []Action{ActionDrop}))
+			var attacks []Attack
+			for _, e := range events {
+				if a, ok := e.(Attack); ok {
+					attacks = append(attacks, a)
+				}
+				if _, ok := e.(GarbageReceived); ok {
+					t.Errorf("Garbage shouldn't come up on a piece that cleared lines")
+				}
+			}
+
+			if !reflect.DeepEqual(attacks, []Attack{c.attack}) {
+				t.Errorf("Expected attack %v, got %v", c.attack, attacks)
+			}
+			if g.PendingGarbage() != c.pending {
+				t.Errorf("Expected %v lines of garbage left, got %v", c.pending, g.PendingGarbage())
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package tetris
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// A block's color. 0 is empty, small numbers are the named colors below, and
+// anything else is a 256 color palette index (ANSIColor) or a true color (RGBColor)
+type Color int
+
+const (
+	ColorEmpty Color = iota
+	ColorGreen
+	ColorYellow
+	ColorRed
+	ColorPurple
+	ColorOrange
+	ColorBlue
+	ColorCyan
+	ColorGray // Garbage, and anything a fumen doesn't know the piece for
+)
+
+var colorNames = []string{"empty", "green", "yellow", "red", "purple", "orange", "blue", "cyan", "gray"}
+
+// Flag bits that mark the kind of color, with the color itself in the bits below them
+const (
+	colorANSI = 1 << 8
+	colorRGB  = 1 << 24
+)
+
+// One of the 256 terminal colors
+func ANSIColor(n uint8) Color {
+	return Color(colorANSI | int(n))
+}
+
+func RGBColor(r, g, b uint8) Color {
+	return Color(colorRGB | int(r)<<16 | int(g)<<8 | int(b))
+}
+
+// The palette index, if it's an ANSIColor
+func (c Color) ANSI() (uint8, bool) {
+	if c >= colorANSI && c < 2*colorANSI {
+		return uint8(c), true
+	}
+	return 0, false
+}
+
+// The red, green and blue parts, if it's an RGBColor
+func (c Color) RGB() (r, g, b uint8, ok bool) {
+	if c >= colorRGB && c < 2*colorRGB {
+		return uint8(c >> 16), uint8(c >> 8), uint8(c), true
+	}
+	return 0, 0, 0, false
+}
+
+// A name for named colors, the palette index for ANSI colors and #rrggbb for true colors
+func (c Color) String() string {
+	if n, ok := c.ANSI(); ok {
+		return strconv.Itoa(int(n))
+	}
+	if r, g, b, ok := c.RGB(); ok {
+		return fmt.Sprintf("#%02x%02x%02x", r, g, b)
+	}
+	if c >= 0 && int(c) < len(colorNames) {
+		return colorNames[c]
+	}
+	return "invalid Color"
+}
+
+// Colors are written the same way String shows them in files, like "red", "208" or "#ff8800"
+func (c Color) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+func (c *Color) UnmarshalText(text []byte) error {
+	s := string(text)
+
+	for i, name := range colorNames {
+		if s == name {
+			*c = Color(i)
+			return nil
+		}
+	}
+
+	var r, g, b uint8
+	if _, err := fmt.Sscanf(s, "#%02x%02x%02x", &r, &g, &b); err == nil && len(s) == 7 {
+		*c = RGBColor(r, g, b)
+		return nil
+	}
+	if n, err := strconv.ParseUint(s, 10, 8); err == nil {
+		*c = ANSIColor(uint8(n))
+		return nil
+	}
+	return fmt.Errorf("unknown color %q", s)
+}
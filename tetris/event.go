@@ -0,0 +1,114 @@
+package tetris
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Something that happened inside the game. Collect them with Game.Events and
+// switch on the type to find out what happened
+type Event interface {
+	event()
+}
+
+// A new piece came in at the top, from the queue or out of hold
+type PieceSpawned struct {
+	Piece Piece
+}
+
+// The active piece locked into the board
+type PieceLocked struct {
+	Piece Piece
+	Clear Clear
+}
+
+// Some lines were cleared. Comes right after the PieceLocked that cleared them
+type LinesCleared struct {
+	Clear Clear
+}
+
+// The active piece was swapped into hold
+type PieceHeld struct {
+	Piece Piece
+}
+
+type LevelUp struct {
+	Level int
+}
+
+// Lines of garbage were pushed up into the board
+type GarbageReceived struct {
+	Lines int
+}
+
+// A line clear's attack (see Clear.Attack). Canceled lines knocked out queued garbage,
+// the rest is for the opponent
+type Attack struct {
+	Lines    int
+	Canceled int
+}
+
+// There was no room for the next piece, the game is over
+type TopOut struct{}
+
+// The stack reached the top under Rules.NoTopOut, so the board was cleared
+type BoardReset struct{}
+
+// The game's goal was done, or can't be done anymore. The game is over
+type Finished struct {
+	Outcome Outcome
+}
+
+func (PieceSpawned) event()    {}
+func (PieceLocked) event()     {}
+func (LinesCleared) event()    {}
+func (PieceHeld) event()       {}
+func (LevelUp) event()         {}
+func (GarbageReceived) event() {}
+func (Attack) event()          {}
+func (TopOut) event()          {}
+func (BoardReset) event()      {}
+func (Finished) event()        {}
+
+// How many uncollected events a game holds on to before it starts dropping the oldest ones
+const maxEvents = 1000
+
+func (g *Game) emit(e Event) {
+	if len(g.events) >= maxEvents {
+		// Nobody's collecting them. Copy what's kept, since clones share the old array
+		g.events = slices.Clone(g.events[len(g.events)-maxEvents/2:])
+	}
+	g.events = append(g.events, e)
+}
+
+// Events since the last call, oldest first. If they aren't collected for a long while
+// the oldest ones are dropped
+func (g *Game) Events() []Event {
+	events := g.events
+	g.events = nil
+	return events
+}
+
+var clearNames = []string{"", "single", "double", "triple", "tetris"}
+
+// Human readable name for the clear, like "back to back T-spin double". Empty if it's not worth mentioning
+func (c Clear) Name() string {
+	var name string
+	if c.Lines() < len(clearNames) {
+		name = clearNames[c.Lines()]
+	} else {
+		name = fmt.Sprintf("%v lines", c.Lines())
+	}
+
+	if c.Spin != SpinNone {
+		name = strings.TrimSpace(fmt.Sprintf("%v %v", c.Spin, name))
+	}
+	if c.BackToBack {
+		name = "back to back " + name
+	}
+	if c.PerfectClear {
+		name += ", perfect clear"
+	}
+	return name
+}
@@ -0,0 +1,144 @@
+package tetris
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+// Just the types of some events, for comparing sequences
+func eventTypes(events []Event) []string {
+	types := make([]string, len(events))
+	for i, e := range events {
+		types[i] = reflect.TypeOf(e).Name()
+	}
+	return types
+}
+
+func TestEvents(t *testing.T) {
+	cases := []struct {
+		name   string
+		setup  func(g *Game)
+		action Action
+		events []string
+	}{
+		{
+			name:   "Drop",
+			action: ActionDrop,
+			events: []string{"PieceLocked", "PieceSpawned"},
+		},
+		{
+			name:   "Hold",
+			action: ActionHold,
+			events: []string{"PieceHeld", "PieceSpawned"},
+		},
+		{
+			name: "Clear",
+			setup: func(g *Game) {
+				fillRow(g, 19, 0)
+				placePiece(g, PieceI, RotationR, Vector{-2, 5})
+			},
+			action: ActionDrop,
+			events: []string{"PieceLocked", "LinesCleared", "PieceSpawned"},
+		},
+		{
+			name: "Level up",
+			setup: func(g *Game) {
+				g.lines = 9
+				fillRow(g, 19, 0)
+				placePiece(g, PieceI, RotationR, Vector{-2, 5})
+			},
+			action: ActionDrop,
+			events: []string{"PieceLocked", "LinesCleared", "LevelUp", "PieceSpawned"},
+		},
+		{
+			name: "Top out",
+			setup: func(g *Game) {
+				for y := 2; y < 20; y++ {
+					fillRow(g, y, 0)
+				}
+			},
+			action: ActionDrop,
+			events: []string{"PieceLocked", "TopOut"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			g := NewGame(20, 10, 0, DefaultRules())
+			if c.setup != nil {
+				c.setup(&g)
+			}
+			g.Events()
+
+			g.Act(c.action)
+			if types := eventTypes(g.Events()); !reflect.DeepEqual(types, c.events) {
+				t.Errorf("Expected events %v, got %v", c.events, types)
+			}
+			if len(g.Events()) != 0 {
+				t.Errorf("Events should be empty after they've been collected")
+			}
+		})
+	}
+}
+
+func TestUncollectedEvents(t *testing.T) {
+	rules := DefaultRules()
+	rules.NoTopOut = true
+	g := NewGame(20, 10, 0, rules)
+	for i := 0; i < maxEvents; i++ {
+		g.Act(ActionDrop)
+	}
+	clone := g.Clone()
+	kept := slices.Clone(clone.events)
+
+	g.Act(ActionHold)
+	events := g.Events()
+	if len(events) > maxEvents {
+		t.Errorf("Expected at most %v events, got %v", maxEvents, len(events))
+	}
+	if types := eventTypes(events[len(events)-2:]); !reflect.DeepEqual(types, []string{"PieceHeld", "PieceSpawned"}) {
+		t.Errorf("Expected the newest events to be kept, got %v", types)
+	}
+	if !reflect.DeepEqual(clone.events, kept) {
+		t.Errorf("Dropping events changed a clone's")
+	}
+}
+
+func TestLinesClearedRows(t *testing.T) {
+	g := NewGame(20, 10, 0, DefaultRules())
+	fillRow(&g, 17, 0)
+	fillRow(&g, 19, 0)
+	placePiece(&g, PieceI, RotationR, Vector{-2, 5})
+	g.Events()
+
+	g.Act(ActionDrop)
+	for _, e := range g.Events() {
+		if e, ok := e.(LinesCleared); ok {
+			if !reflect.DeepEqual(e.Clear.Rows, []int{17, 19}) {
+				t.Errorf("Expected rows 17 and 19 to clear, got %v", e.Clear.Rows)
+			}
+			return
+		}
+	}
+	t.Errorf("No LinesCleared event")
+}
+
+func TestClearName(t *testing.T) {
+	cases := []struct {
+		clear Clear
+		name  string
+	}{
+		{Clear{Rows: rows(4)}, "tetris"},
+		{Clear{Spin: SpinFull}, "T-spin"},
+		{Clear{Rows: rows(2), Spin: SpinFull, BackToBack: true}, "back to back T-spin double"},
+		{Clear{Rows: rows(1), Spin: SpinMini}, "mini T-spin single"},
+		{Clear{Rows: rows(2), PerfectClear: true}, "double, perfect clear"},
+	}
+
+	for _, c := range cases {
+		if name := c.clear.Name(); name != c.name {
+			t.Errorf("Expected %q, got %q", c.name, name)
+		}
+	}
+}
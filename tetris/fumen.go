@@ -0,0 +1,434 @@
+package tetris
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Fumen (https://harddrop.com/fumen/) v115 data is a base64ish string of pages. Each page
+// is a diff of a 10 wide field against the page before it, run length encoded, followed by
+// the piece placed on that page and an optional comment. Only the first page's field is
+// turned into a board, but every page is read for pieces. Later fields are diffed against the
+// page before with its piece locked in, which we don't play out, so they're skipped over unchecked.
+
+const (
+	fumenPrefix = "v115@"
+	fumenChars  = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+	fumenBase   = len(fumenChars)
+	fumenWidth  = 10
+	fumenHeight = 23                             // Visible rows. There's one more under them for garbage
+	fumenCells  = (fumenHeight + 1) * fumenWidth // Every cell in a field, garbage row included
+	fumenGray   = 8                              // Garbage blocks
+	fumenBlank  = (fumenGray+1)*fumenCells - 1   // A field with nothing changed from the last page
+	fumenQuiz   = "#Q="
+	// Comments are printable ascii packed 4 characters to every 5 chars, with their length in 2 chars
+	fumenCommentFirst = ' '
+	fumenCommentChars = 96
+	fumenMaxComment   = fumenBase * fumenBase
+)
+
+// Fumen's block types, indexed by their number. 0 is empty and 8 is gray
+var fumenPieces = [...]string{1: "I", 2: "L", 3: "O", 4: "Z", 5: "T", 6: "J", 7: "S"}
+
+// Turn a fumen into a setup for a height x width board. The board comes from the first page.
+// The pieces come from a quiz comment (#Q=[hold](current)next) if there is one, and
+// from the pieces placed on each page otherwise
+func DecodeFumen(data string, height, width int) (Setup, error) {
+	if width != fumenWidth {
+		return Setup{}, fmt.Errorf("fumen boards are %v wide, not %v", fumenWidth, width)
+	}
+
+	// Allow whole urls, and the ?s some sites break long fumens up with
+	start := strings.Index(data, fumenPrefix)
+	if start < 0 {
+		return Setup{}, fmt.Errorf("not a v115 fumen")
+	}
+	data = strings.ReplaceAll(strings.TrimSpace(data[start+len(fumenPrefix):]), "?", "")
+	r := fumenReader{data: data}
+
+	var (
+		setup   Setup
+		placed  []string
+		field   = make([]int, fumenCells)
+		comment string
+		repeats int // Pages left that reuse the last field
+	)
+
+	for page := 0; page == 0 || r.more(); page++ {
+		if repeats > 0 {
+			repeats--
+		} else {
+			var err error
+			if repeats, err = r.field(field); err != nil {
+				return Setup{}, err
+			}
+		}
+
+		if page == 0 {
+			board, err := fumenBoard(field, height)
+			if err != nil {
+				return Setup{}, err
+			}
+			setup.Board = board
+			field = nil
+		}
+
+		action, err := r.read(3)
+		if err != nil {
+			return Setup{}, err
+		}
+		piece := action % 8
+		hasComment := action/(8*4*fumenCells*2*2*2)%2 == 1
+
+		if piece >= fumenGray {
+			return Setup{}, fmt.Errorf("page %v places a gray piece", page+1)
+		}
+		if piece > 0 {
+			placed = append(placed, fumenPieces[piece])
+		}
+
+		if hasComment {
+			text, err := r.comment()
+			if err != nil {
+				return Setup{}, err
+			}
+			if page == 0 {
+				comment = text
+			}
+		}
+	}
+
+	if strings.HasPrefix(comment, fumenQuiz) {
+		hold, seq, err := parseQuiz(comment)
+		if err != nil {
+			return Setup{}, err
+		}
+		setup.Hold, setup.Sequence = hold, seq
+	} else {
+		setup.Sequence = placed
+	}
+	return setup, nil
+}
+
+// Turn a setup into a one page fumen. The pieces go in a quiz comment, which is how most
+// tools share "what comes next"
+func EncodeFumen(setup Setup) (string, error) {
+	height := len(setup.Board)
+	if height > 0 && len(setup.Board[0]) != fumenWidth {
+		return "", fmt.Errorf("fumen boards are %v wide, not %v", fumenWidth, len(setup.Board[0]))
+	}
+
+	types := fumenTypes()
+	field := make([]int, fumenCells)
+	for y, row := range setup.Board {
+		fy := y - height + fumenHeight // Boards sit on the bottom of the field
+		for x, c := range row {
+			if c == 0 {
+				continue
+			}
+			if fy < 0 {
+				return "", fmt.Errorf("board is taller than a fumen (%v rows)", fumenHeight)
+			}
+
+			t, ok := types[c]
+			if !ok {
+				t = fumenGray
+			}
+			field[fy*fumenWidth+x] = t
+		}
+	}
+
+	var w fumenWriter
+	w.field(field)
+
+	var quiz string
+	if len(setup.Sequence) > 0 || setup.Hold != "" {
+		quiz = fumenQuiz + "[" + setup.Hold + "]"
+		if len(setup.Sequence) > 0 {
+			quiz += "(" + setup.Sequence[0] + ")" + strings.Join(setup.Sequence[1:], "")
+		}
+	}
+
+	// No piece, colors on, locked. Only the comment flag changes
+	flags := 1 << 2 // rise, mirror, color, comment, !lock from the lowest bit up
+	if quiz != "" {
+		flags |= 1 << 3
+	}
+	w.write(flags*fumenCells*4*8, 3)
+
+	if quiz != "" {
+		if err := w.comment(quiz); err != nil {
+			return "", err
+		}
+	}
+	return fumenPrefix + w.sb.String(), nil
+}
+
+// The board and upcoming pieces as a fumen, to take the game somewhere else.
+// The active piece is left off the board and put at the front of the pieces.
+// Fumen only knows the standard pieces, so games with other piece sets just get the board
+func (g Game) Fumen() (string, error) {
+	setup := Setup{Board: g.board}
+	if set, _ := PieceSetNamed(g.rules.PieceSet); set.Name != StandardPieceSet {
+		return EncodeFumen(setup)
+	}
+
+	if g.phase == phaseFalling {
+		setup.Sequence = append(setup.Sequence, g.piece.name)
+	}
+	for _, p := range g.Next(g.rules.Previews) {
+		setup.Sequence = append(setup.Sequence, p.name)
+	}
+	if g.hold != nil {
+		setup.Hold = g.hold.name
+	}
+
+	return EncodeFumen(setup)
+}
+
+// Colors of the standard pieces to fumen block types
+func fumenTypes() map[int]int {
+	types := map[int]int{}
+	for t, name := range fumenPieces {
+		if i := pieceIndex(Pieces, name); i >= 0 && name != "" {
+			types[Pieces[i].color] = t
+		}
+	}
+	return types
+}
+
+// Cut the bottom height rows out of a field and color them like the standard pieces
+func fumenBoard(field []int, height int) ([][]int, error) {
+	board := NewBoard(height, fumenWidth)
+
+	for fy := 0; fy < fumenHeight; fy++ {
+		y := fy - fumenHeight + height
+		for x := 0; x < fumenWidth; x++ {
+			t := field[fy*fumenWidth+x]
+			if t == 0 {
+				continue
+			}
+			if y < 0 {
+				return nil, fmt.Errorf("fumen has blocks above the top of a %v row board", height)
+			}
+
+			if t == fumenGray {
+				board[y][x] = int(ColorGray)
+			} else {
+				board[y][x] = Pieces[pieceIndex(Pieces, fumenPieces[t])].color
+			}
+		}
+	}
+	return board, nil
+}
+
+// #Q=[hold](current)next
+func parseQuiz(comment string) (string, []string, error) {
+	quiz := strings.TrimPrefix(comment, fumenQuiz)
+	bad := fmt.Errorf("can't read quiz %q", comment)
+
+	hold, rest, ok := strings.Cut(strings.TrimPrefix(quiz, "["), "]")
+	if !ok || !strings.HasPrefix(quiz, "[") {
+		return "", nil, bad
+	}
+	current, next, ok := strings.Cut(strings.TrimPrefix(rest, "("), ")")
+	if !ok || !strings.HasPrefix(rest, "(") {
+		return "", nil, bad
+	}
+
+	var seq []string
+	for _, c := range current + next {
+		name := string(c)
+		if pieceIndex(Pieces, name) < 0 {
+			// Anything after the pieces is just more comment
+			if c == ';' || c == ' ' {
+				break
+			}
+			return "", nil, bad
+		}
+		seq = append(seq, name)
+	}
+	if hold != "" && pieceIndex(Pieces, hold) < 0 {
+		return "", nil, bad
+	}
+	return hold, seq, nil
+}
+
+type fumenReader struct {
+	data string
+	pos  int
+}
+
+func (r fumenReader) more() bool {
+	return r.pos < len(r.data)
+}
+
+// Read an n char little endian number
+func (r *fumenReader) read(n int) (int, error) {
+	value, scale := 0, 1
+	for i := 0; i < n; i++ {
+		if !r.more() {
+			return 0, fmt.Errorf("fumen ended early")
+		}
+		v := strings.IndexByte(fumenChars, r.data[r.pos])
+		if v < 0 {
+			return 0, fmt.Errorf("fumen has a bad character %q", r.data[r.pos])
+		}
+
+		value += v * scale
+		scale *= fumenBase
+		r.pos++
+	}
+	return value, nil
+}
+
+// Apply a page's diff to field, or just read past it if field is nil.
+// Returns how many of the following pages reuse it
+func (r *fumenReader) field(field []int) (int, error) {
+	for i := 0; i < fumenCells; {
+		run, err := r.read(2)
+		if err != nil {
+			return 0, err
+		}
+		diff, count := run/fumenCells-fumenGray, run%fumenCells+1
+		if i+count > fumenCells {
+			return 0, fmt.Errorf("fumen field runs past the end")
+		}
+
+		for j := i; j < i+count && field != nil; j++ {
+			field[j] += diff
+			if field[j] < 0 || field[j] > fumenGray {
+				return 0, fmt.Errorf("fumen has a bad block")
+			}
+		}
+		i += count
+
+		if run == fumenBlank {
+			return r.read(1)
+		}
+	}
+	return 0, nil
+}
+
+func (r *fumenReader) comment() (string, error) {
+	length, err := r.read(2)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	for sb.Len() < length {
+		v, err := r.read(5)
+		if err != nil {
+			return "", err
+		}
+		for i := 0; i < 4 && sb.Len() < length; i++ {
+			sb.WriteByte(byte(v%fumenCommentChars) + fumenCommentFirst)
+			v /= fumenCommentChars
+		}
+	}
+	return unescape(sb.String()), nil
+}
+
+type fumenWriter struct {
+	sb strings.Builder
+}
+
+// Write value as an n char little endian number
+func (w *fumenWriter) write(value, n int) {
+	for i := 0; i < n; i++ {
+		w.sb.WriteByte(fumenChars[value%fumenBase])
+		value /= fumenBase
+	}
+}
+
+// The first page's field, diffed against an empty one
+func (w *fumenWriter) field(field []int) {
+	runs := 0
+	for i := 0; i < fumenCells; {
+		count := 1
+		for i+count < fumenCells && field[i+count] == field[i] {
+			count++
+		}
+		w.write((field[i]+fumenGray)*fumenCells+count-1, 2)
+		i += count
+		runs++
+	}
+
+	if runs == 1 && field[0] == 0 {
+		w.write(0, 1) // Blank, and no pages after it repeat it
+	}
+}
+
+func (w *fumenWriter) comment(text string) error {
+	text = escape(text)
+	if len(text) >= fumenMaxComment {
+		return fmt.Errorf("comment is too long for a fumen")
+	}
+
+	w.write(len(text), 2)
+	for i := 0; i < len(text); i += 4 {
+		value, scale := 0, 1
+		for j := i; j < min(i+4, len(text)); j++ {
+			value += int(text[j]-fumenCommentFirst) * scale
+			scale *= fumenCommentChars
+		}
+		w.write(value, 5)
+	}
+	return nil
+}
+
+// Comments are escaped like javascript's escape(), which fumen was written with
+func escape(s string) string {
+	var sb strings.Builder
+	for _, c := range s {
+		switch {
+		case c < utf8.RuneSelf && (isAlnum(byte(c)) || strings.ContainsRune("@*_+-./", c)):
+			sb.WriteRune(c)
+		case c < 0x100:
+			fmt.Fprintf(&sb, "%%%02X", c)
+		default:
+			fmt.Fprintf(&sb, "%%u%04X", c)
+		}
+	}
+	return sb.String()
+}
+
+func unescape(s string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '%' {
+			if c, n, ok := unescapeAt(s[i+1:]); ok {
+				sb.WriteRune(c)
+				i += n
+				continue
+			}
+		}
+		sb.WriteByte(s[i])
+	}
+	return sb.String()
+}
+
+// Decode the %XX or %uXXXX after a %. n is how many characters it took up
+func unescapeAt(s string) (c rune, n int, ok bool) {
+	n = 2
+	if strings.HasPrefix(s, "u") {
+		s, n = s[1:], 5
+	}
+	digits := min(n, 4)
+	if len(s) < digits {
+		return 0, 0, false
+	}
+
+	v, err := strconv.ParseUint(s[:digits], 16, 32)
+	if err != nil {
+		return 0, 0, false
+	}
+	return rune(v), n, true
+}
+
+func isAlnum(c byte) bool {
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')
+}
@@ -0,0 +1,120 @@
+package tetris
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDecodeFumen(t *testing.T) {
+	l, i := Pieces[PieceL].color, Pieces[PieceI].color
+
+	tests := []struct {
+		name   string
+		fumen  string
+		bottom [][]int // Bottom rows of the board, everything above is empty
+		seq    []string
+	}{
+		{"empty", "v115@vhAAgH", nil, nil},
+		{"blocks", "v115@HhglIeglIehlAezhMeAgH", [][]int{
+			{l, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+			{l, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+			{l, l, 0, i, i, i, i, 0, 0, 0},
+		}, nil},
+		{"url", "https://harddrop.com/fumen/?v115@HhglIeglIe?hlAezhMeAgH", [][]int{
+			{l, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+			{l, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+			{l, l, 0, i, i, i, i, 0, 0, 0},
+		}, nil},
+		// Two pages, placing a T then an I
+		{"placed pieces", "v115@vhBVQJRwB", nil, []string{"T", "I"}},
+		// An I locked flat on the bottom row, then erased from the field on the next page
+		{"edits after a lock", "v115@vhARQJehTaMeAgH", nil, []string{"I"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			setup, err := DecodeFumen(test.fumen, 20, 10)
+			if err != nil {
+				t.Fatalf("Couldn't decode %v: %v", test.fumen, err)
+			}
+
+			expected := NewBoard(20, 10)
+			copy(expected[20-len(test.bottom):], test.bottom)
+			if !reflect.DeepEqual(setup.Board, expected) {
+				t.Errorf("Decoded board %v, expected %v", setup.Board, expected)
+			}
+			if !reflect.DeepEqual(setup.Sequence, test.seq) {
+				t.Errorf("Decoded pieces %v, expected %v", setup.Sequence, test.seq)
+			}
+		})
+	}
+}
+
+func TestDecodeBadFumens(t *testing.T) {
+	for _, fumen := range []string{
+		"",
+		"v114@vhAAgH",
+		"v115@vhAA",      // Ends early
+		"v115@vh!AgH",    // Not base64
+		"v115@zhAAgH",    // Runs past the end of the field
+		"v115@HhglIeglI", // Ends mid field
+	} {
+		if _, err := DecodeFumen(fumen, 20, 10); err == nil {
+			t.Errorf("Decoded %q without an error", fumen)
+		}
+	}
+
+	if _, err := DecodeFumen("v115@vhAAgH", 20, 8); err == nil {
+		t.Errorf("Decoded a fumen onto an 8 wide board without an error")
+	}
+	// The L sticks up into the 3rd row from the bottom
+	if _, err := DecodeFumen("v115@HhglIeglIehlAezhMeAgH", 2, 10); err == nil {
+		t.Errorf("Decoded a fumen onto a board too short for it without an error")
+	}
+}
+
+func TestFumenRoundTrip(t *testing.T) {
+	for _, seed := range []uint64{1, 2, 3} {
+		g := NewGame(20, 10, seed, DefaultRules())
+		playRandomly(&g, seed, 200)
+		g.Act(ActionHold)
+
+		fumen, err := g.Fumen()
+		if err != nil {
+			t.Fatalf("Couldn't encode: %v", err)
+		}
+		setup, err := DecodeFumen(fumen, 20, 10)
+		if err != nil {
+			t.Fatalf("Couldn't decode %v: %v", fumen, err)
+		}
+		loaded, err := NewGameFrom(20, 10, seed, DefaultRules(), setup)
+		if err != nil {
+			t.Fatalf("Couldn't start a game from %v: %v", fumen, err)
+		}
+
+		if !reflect.DeepEqual(loaded.board, g.board) {
+			t.Errorf("Seed %v: board changed going through %v", seed, fumen)
+		}
+		if loaded.piece.name != g.piece.name || !reflect.DeepEqual(loaded.Next(5), g.Next(5)) {
+			t.Errorf("Seed %v: pieces changed going through %v", seed, fumen)
+		}
+		if hold, _ := g.Hold(); loaded.hold == nil || loaded.hold.name != hold.name {
+			t.Errorf("Seed %v: held piece changed going through %v", seed, fumen)
+		}
+	}
+}
+
+func TestFumenComments(t *testing.T) {
+	for _, s := range []string{"", "#Q=[](T)IO", "100% PC; (maybe)", "ünïcode ✓"} {
+		if got := unescape(escape(s)); got != s {
+			t.Errorf("%q came back as %q", s, got)
+		}
+	}
+
+	var w fumenWriter
+	w.comment("#Q=[S](T)IO")
+	r := fumenReader{data: w.sb.String()}
+	if got, err := r.comment(); err != nil || got != "#Q=[S](T)IO" {
+		t.Errorf("Comment came back as %q (%v)", got, err)
+	}
+}
@@ -4,18 +4,161 @@ import "fmt"
 
 type Game struct {
 	board    [][]int
+	pieces   []Piece
+	rand     Randomizer
+	seed     uint64
+	rules    Rules
+	setup    Setup
 	piece    Piece
+	rot      Rotation
 	pos      Vector
+	queue    []Piece // Upcoming pieces, at least Rules.Previews long
+	hold     *Piece
+	holdUsed bool      // Only one hold per piece, reset when a piece locks
+	garbage  []Garbage // Queued to come up on the next lock
+	lock     lockState
+	lastKick int // Kick used by the last rotation, -1 if the piece has moved since
+	scorer   Scorer
+	combo    int  // Consecutive line clears, -1 when the last piece didn't clear anything
+	b2b      bool // Was the last line clear difficult?
+	level    int
+	lines    int
+	events   []Event
+	outcome  Outcome
+	progress goalProgress
+	phase    phase
+	delay    int     // Frames left before the next piece comes in
+	gravity  float64 // Gravity built up towards the next row
+	frames   int
+	inputs   []ReplayInput // Every action since the game was cloned from another, for replays
+	earlier  *inputChunk   // Actions from before that
 	height   int
 	width    int
 	score    int
 	GameOver bool
 }
 
+// Rules are the knobs that stay fixed for the whole game
+type Rules struct {
+	Randomizer RandomizerType
+	Previews   int // How many upcoming pieces Next will show
+	LockDelay  int // Frames a piece can sit on the stack before it locks. 0 locks as soon as it can't fall
+	LockReset  LockResetPolicy
+	// Frames between a piece locking and the next one coming in (entry delay)
+	ARE int
+	// Extra frames on top of ARE when the lock cleared lines
+	LineClearDelay int
+	StartLevel     int
+	LevelRule      LevelRule
+	// Only for LevelFixedGoal
+	LinesPerLevel int
+	// What finishes the game, besides topping out
+	Goal Goal
+	// Name of a registered PieceSet, empty for the standard pieces
+	PieceSet string
+	// Clear the board instead of ending the game when the stack reaches the top
+	NoTopOut bool
+}
+
+func DefaultRules() Rules {
+	return Rules{
+		Randomizer: Randomizer7Bag,
+		Previews:   5,
+		LockDelay:  30,
+		LockReset:  LockResetMove,
+		StartLevel: 1,
+		LevelRule:  LevelFixedGoal,
+		// No entry or line clear delay, like most modern games
+		ARE:            0,
+		LineClearDelay: 0,
+
+		LinesPerLevel: 10,
+	}
+}
+
 func (g Game) Score() int {
 	return g.score
 }
 
+// The seed the game's randomizer was started with
+func (g Game) Seed() uint64 {
+	return g.seed
+}
+
+func (g Game) Rules() Rules {
+	return g.rules
+}
+
+// Up to n of the upcoming pieces, in the order they'll be dealt. Never more than Rules.Previews
+func (g Game) Next(n int) []Piece {
+	n = min(n, g.rules.Previews, len(g.queue))
+	next := make([]Piece, max(n, 0))
+	copy(next, g.queue)
+	return next
+}
+
+// The pieces the game is played with
+func (g Game) Pieces() []Piece {
+	pieces := make([]Piece, len(g.pieces))
+	copy(pieces, g.pieces)
+	return pieces
+}
+
+// Swap out how points are given. Games start with GuidelineScorer
+func (g *Game) SetScorer(s Scorer) {
+	g.scorer = s
+}
+
+// The held piece, if there is one
+func (g Game) Hold() (Piece, bool) {
+	if g.hold == nil {
+		return Piece{}, false
+	}
+	return *g.hold, true
+}
+
+// The falling piece and where it is, X and Y being the top left of its box
+type ActivePiece struct {
+	Piece    Piece
+	Rotation Rotation
+	X, Y     int
+}
+
+// The falling piece. There isn't one between pieces or once the game is over
+func (g Game) Active() (ActivePiece, bool) {
+	if g.GameOver || g.phase != phaseFalling {
+		return ActivePiece{}, false
+	}
+	return ActivePiece{Piece: g.piece, Rotation: g.rot, X: g.pos.x, Y: g.pos.y}, true
+}
+
+// Can the active piece be held? Only once per piece
+func (g Game) CanHold() bool {
+	return !g.holdUsed
+}
+
+// A copy of the game that can be played on without touching the original, for trying things out.
+// Only the board and the randomizer are actually copied, everything else (like the inputs recorded
+// for the replay) is shared until it's changed
+func (g Game) Clone() Game {
+	c := g
+	c.board = NewBoard(g.height, g.width)
+	for y := range c.board {
+		copy(c.board[y], g.board[y])
+	}
+	c.rand = cloneRandomizer(g.rand)
+
+	// Appending to these has to make a new array instead of writing into the shared one
+	c.queue = g.queue[:len(g.queue):len(g.queue)]
+	c.garbage = g.garbage[:len(g.garbage):len(g.garbage)]
+	c.events = g.events[:len(g.events):len(g.events)]
+	if len(g.inputs) > 0 {
+		c.earlier = &inputChunk{prev: g.earlier, inputs: g.inputs[:len(g.inputs):len(g.inputs)], total: g.inputCount()}
+		c.inputs = nil
+	}
+	return c
+}
+
 func NewBoard(height, width int) [][]int {
 	board := make([][]int, height)
 	blocks := make([]int, height*width)
@@ -30,35 +173,91 @@ func NewBoard(height, width int) [][]int {
 	return board
 }
 
-// Returns false if there wasn't room for another piece
+// Returns false if there wasn't room for another piece, or there are no pieces left
 func (g *Game) nextPieceIfPossible() bool {
-	piece := RandomPiece()
-	pos := Vector{x: int(g.width / 2), y: 0 - piece.yOffset()}
+	p, ok := g.takeNext()
+	return ok && g.spawn(p)
+}
 
-	for _, b := range piece.shape {
-		if c, ok := g.colorAt(pos.add(b)); !ok || c > 0 {
-			return false
+// Pop the front of the queue and top it back up from the randomizer.
+// Returns false if the randomizer has run out of pieces
+func (g *Game) takeNext() (Piece, bool) {
+	g.fillQueue()
+	if len(g.queue) == 0 {
+		return Piece{}, false
+	}
+
+	p := g.queue[0]
+	g.queue = g.queue[1:]
+	g.fillQueue()
+	return p, true
+}
+
+func (g *Game) fillQueue() {
+	for len(g.queue) < max(g.rules.Previews, 1) {
+		idx := g.rand.Next()
+		if idx < 0 {
+			return
 		}
+		g.queue = append(g.queue, g.pieces[idx])
+	}
+}
+
+// Put piece at the spawn position in its spawn orientation. Returns false if it doesn't fit
+func (g *Game) spawn(piece Piece) bool {
+	pos := Vector{x: (g.width - piece.size) / 2, y: 0 - piece.yOffset()}.add(piece.spawn)
+
+	if !g.fits(piece.states[Rotation0], pos) {
+		return false
 	}
 
 	g.pos = pos
 	g.piece = piece
+	g.rot = Rotation0
+	g.phase = phaseFalling
+	g.gravity = 0
+	g.lock = lockState{lowest: pos.y}
+	g.lastKick = -1
+	g.emit(PieceSpawned{Piece: piece})
 	return true
 }
 
-func NewGame(height, width int, piece Piece) Game {
+// Start a game with the rules' piece set. Games with the same seed and rules deal the same pieces.
+// Panics if the piece set isn't registered, NewGameFrom returns an error instead
+func NewGame(height, width int, seed uint64, rules Rules) Game {
+	pieces, err := rules.pieces()
+	if err != nil {
+		panic(err)
+	}
+
+	g := newGame(height, width, pieces, rules.randomizer(seed, len(pieces)), rules)
+	g.seed = seed
+	return g
+}
+
+func newGame(height, width int, pieces []Piece, r Randomizer, rules Rules) Game {
+	g := blankGame(height, width, pieces, r, rules)
+	g.nextPieceIfPossible()
+	return g
+}
+
+// A game with nothing dealt yet
+func blankGame(height, width int, pieces []Piece, r Randomizer, rules Rules) Game {
 	// Initialize board
 	board := NewBoard(height, width)
 
-	g := Game{
+	return Game{
 		height:   height,
 		width:    width,
 		board:    board,
+		pieces:   pieces,
+		rand:     r,
+		rules:    rules,
+		scorer:   GuidelineScorer{},
+		combo:    -1,
+		level:    max(rules.StartLevel, 1),
 		GameOver: false,
 	}
-
-	g.nextPieceIfPossible()
-	return g
 }
 
 func (g Game) isInBounds(v Vector) bool {
@@ -81,6 +280,21 @@ func (g Game) colorAt(v Vector) (int, bool) {
 	return 0, false
 }
 
+// Would the blocks of a shape placed at pos all be in bounds and on empty cells?
+func (g Game) fits(shape []Vector, pos Vector) bool {
+	for _, b := range shape {
+		if c, ok := g.colorAt(pos.add(b)); !ok || c > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// The active piece's blocks in its current orientation, relative to g.pos
+func (g Game) shape() []Vector {
+	return g.piece.states[g.rot]
+}
+
 // Returns a [][]int of the board with the piece "colored" in
 func (g Game) Board() [][]int {
 	// make copy of board
@@ -89,13 +303,18 @@ func (g Game) Board() [][]int {
 		copy(b[i], g.board[i])
 	}
 
+	// No piece to draw while waiting for the next one
+	if g.phase != phaseFalling {
+		return b
+	}
+
 	// set piece's shape colors on to board
-	for _, v := range g.piece.shape {
+	for _, v := range g.shape() {
 		pos := g.pos.add(v)
 
 		// Should never happen because bounds checks exist everywhere piece is moved/placed
 		if !g.isInBounds(pos) {
-			msg := fmt.Sprintf("Tried to GetBoard() with a piece shape block that's out of bounds. Pos: %v, Shape: %v", g.pos, g.piece.shape)
+			msg := fmt.Sprintf("Tried to GetBoard() with a piece shape block that's out of bounds. Pos: %v, Shape: %v", g.pos, g.shape())
 			panic(msg)
 		}
 
@@ -105,36 +324,53 @@ func (g Game) Board() [][]int {
 	return b
 }
 
+// The board without the active piece
+func (g Game) Stack() [][]int {
+	b := NewBoard(g.height, g.width)
+	for i := range b {
+		copy(b[i], g.board[i])
+	}
+	return b
+}
+
 // Would any new positions in the shape blocks become out of bounds?
 func (g *Game) moveIfPossible(direction Vector) bool {
-	for _, s := range g.piece.shape {
-		newPos := g.pos.add(s).add(direction)
-		if c, inBounds := g.colorAt(newPos); !inBounds || c > 0 {
-			return false
-		}
+	newPos := g.pos.add(direction)
+	if !g.fits(g.shape(), newPos) {
+		return false
 	}
 
-	g.pos = g.pos.add(direction)
+	g.pos = newPos
+	g.lastKick = -1
 	return true
 }
 
-// argument is true for clockwise, false for counterclockwise. Returns true if it rotated, false if it couldn't
-func (g *Game) rotateIfPossible() bool {
-	newPiece := g.piece.rotate()
-	for _, s := range newPiece.shape {
-		if c, ok := g.colorAt(g.pos.add(s)); !ok || c > 0 {
-			return false
+// argument is true for clockwise, false for counterclockwise. Returns true if it rotated, false if it couldn't.
+// Tries each of the piece's SRS kicks in order and takes the first one that fits
+func (g *Game) rotateIfPossible(clockwise bool) bool {
+	to := g.rot.ccw()
+	if clockwise {
+		to = g.rot.cw()
+	}
+
+	shape := g.piece.states[to]
+	for i, k := range g.piece.kicksFor(g.rot, to) {
+		newPos := g.pos.add(Vector{k.x, -k.y}) // kick tables are y-up
+		if g.fits(shape, newPos) {
+			g.pos = newPos
+			g.rot = to
+			g.lastKick = i
+			return true
 		}
 	}
 
-	g.piece = newPiece
-	return true
+	return false
 }
 
-// TODO: Make this return the number of completed lines, use this for score externally instead
-func (g *Game) compactLines() {
+// Remove complete lines, returning which rows they were
+func (g *Game) compactLines() Clear {
 	var broken bool
-	completedLines := 0
+	var clear Clear
 
 	for y := range g.board {
 		broken = false
@@ -146,7 +382,7 @@ func (g *Game) compactLines() {
 		}
 
 		if !broken { // Line is complete
-			completedLines++
+			clear.Rows = append(clear.Rows, y)
 
 			// move above lines down
 			for i := y; i > 0; i-- {
@@ -158,29 +394,146 @@ func (g *Game) compactLines() {
 		}
 	}
 
-	g.score += (2 ^ completedLines*100)
+	clear.PerfectClear = clear.Lines() > 0 && g.boardEmpty()
+	return clear
 }
 
-func (g *Game) Fall() { // Maybe this should return score as well? idk
-	moved := g.moveIfPossible(Vector{0, 1})
+func (g Game) boardEmpty() bool {
+	for y := range g.board {
+		for x := range g.board[y] {
+			if g.board[y][x] > 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Keep the combo and back to back counts going and score the clear
+func (g *Game) scoreClear(clear *Clear) {
+	if clear.Lines() > 0 {
+		g.combo++
+		clear.BackToBack = clear.Difficult() && g.b2b
+		g.b2b = clear.Difficult()
+	} else {
+		g.combo = -1
+	}
+	clear.Combo = g.combo
+	clear.Level = g.level
+
+	g.score += g.scorer.LockScore(*clear)
+	g.addLines(clear.Lines())
+}
+
+// Copy the piece into the board, clear lines and bring in the next piece
+func (g *Game) lockPiece() Clear {
+	spin := g.spin()
+	g.board = g.Board()
+	// It's part of the board now, so garbage coming up doesn't push it too
+	g.phase = phaseEntry
+	g.holdUsed = false
+
+	clear := g.compactLines()
+	clear.Spin = spin
+	level := g.level
+	g.scoreClear(&clear)
+
+	g.emit(PieceLocked{Piece: g.piece, Clear: clear})
+	if clear.Lines() > 0 {
+		g.emit(LinesCleared{Clear: clear})
+	}
+	if g.level > level {
+		g.emit(LevelUp{Level: g.level})
+	}
 
-	if !moved { // Then we've reached the bottom
-		g.board = g.Board()
-		g.compactLines()
-		if !g.nextPieceIfPossible() {
-			g.GameOver = true
+	// Clearing lines fights off garbage, and holds the rest back until a piece locks without clearing anything
+	g.attack(clear)
+	if clear.Lines() == 0 {
+		g.applyGarbage()
+		if g.GameOver {
+			return clear
 		}
 	}
+
+	g.checkGoal(clear)
+	if !g.GameOver {
+		g.startEntry(clear.Lines() > 0)
+	}
+	return clear
 }
 
-// Instantly fall
-func (g *Game) drop() {
-	for {
-		if !g.moveIfPossible(Vector{0, 1}) {
-			g.Fall()
-			break
+func (g *Game) topOut() {
+	g.phase = phaseOver
+	g.GameOver = true
+	g.outcome = OutcomeLost
+	g.emit(TopOut{})
+}
+
+// Bring a piece in, or top out if it doesn't fit. Under Rules.NoTopOut the board is cleared instead
+func (g *Game) spawnOrTopOut(p Piece) {
+	if g.spawn(p) {
+		return
+	}
+	if g.rules.NoTopOut {
+		g.resetBoard()
+		if g.spawn(p) {
+			return
 		}
 	}
+	g.topOut()
+}
+
+// The stack went off the top. Under Rules.NoTopOut the board is cleared and the active piece starts over
+func (g *Game) overflow() {
+	if !g.rules.NoTopOut {
+		g.topOut()
+		return
+	}
+
+	g.resetBoard()
+	if g.phase == phaseFalling {
+		g.spawnOrTopOut(g.piece)
+	}
+}
+
+func (g *Game) resetBoard() {
+	g.board = NewBoard(g.height, g.width)
+	g.emit(BoardReset{})
+}
+
+// Instantly fall and lock
+func (g *Game) drop() {
+	landing := g.landingPos()
+	if cells := landing.y - g.pos.y; cells > 0 {
+		g.pos = landing
+		g.lastKick = -1
+		g.score += g.scorer.DropScore(cells, true)
+	}
+	g.lockPiece()
+}
+
+// Where the active piece would end up after a drop
+func (g Game) landingPos() Vector {
+	pos := g.pos
+	for g.fits(g.shape(), pos.add(Vector{0, 1})) {
+		pos = pos.add(Vector{0, 1})
+	}
+	return pos
+}
+
+// Board sized grid with the active piece's color where it would land after a drop, 0 everywhere else
+func (g Game) Ghost() [][]int {
+	b := NewBoard(g.height, g.width)
+	if g.GameOver || g.phase != phaseFalling {
+		return b
+	}
+
+	pos := g.landingPos()
+	for _, v := range g.shape() {
+		p := pos.add(v)
+		b[p.y][p.x] = g.piece.color
+	}
+	return b
 }
 
 type Action int
@@ -189,21 +542,60 @@ const (
 	ActionLeft Action = iota
 	ActionRight
 	ActionDown
-	ActionRotate
+	ActionRotate // Clockwise
+	ActionRotateCCW
 	ActionDrop
+	ActionHold
 )
 
 func (g *Game) Act(a Action) {
+	// Nothing to move between pieces
+	if g.GameOver || g.phase != phaseFalling {
+		return
+	}
+	g.record(a)
+
 	switch a {
 	case ActionRight:
-		g.moveIfPossible(Vector{1, 0})
+		g.moved(g.moveIfPossible(Vector{1, 0}))
 	case ActionLeft:
-		g.moveIfPossible(Vector{-1, 0})
+		g.moved(g.moveIfPossible(Vector{-1, 0}))
 	case ActionDown:
-		g.moveIfPossible(Vector{0, 1})
+		if g.moveIfPossible(Vector{0, 1}) {
+			g.stepped()
+			g.score += g.scorer.DropScore(1, false)
+		}
 	case ActionRotate:
-		g.rotateIfPossible()
+		g.moved(g.rotateIfPossible(true))
+	case ActionRotateCCW:
+		g.moved(g.rotateIfPossible(false))
 	case ActionDrop:
 		g.drop()
+	case ActionHold:
+		g.holdIfPossible()
+	}
+}
+
+// Swap the active piece into the hold slot and bring the held piece (or the next one) in at the spawn position.
+// Only works once until the next piece locks
+func (g *Game) holdIfPossible() bool {
+	if g.holdUsed {
+		return false
+	}
+
+	current, held := g.piece, g.hold
+	g.hold = &current
+	g.holdUsed = true
+	g.emit(PieceHeld{Piece: current})
+
+	if held == nil {
+		p, ok := g.takeNext()
+		if !ok {
+			g.finish(OutcomeLost)
+			return true
+		}
+		held = &p
 	}
+	g.spawnOrTopOut(*held)
+	return true
 }
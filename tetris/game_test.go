@@ -1,13 +1,19 @@
 package tetris
 
 import (
+	"reflect"
 	"testing"
 )
 
-var testPiece = Piece{shape: []Vector{{0, 0}}, color: 1, canRotate: false}
+var testPiece = newPiece("test", 1, nil, "#")
+
+// A game that only ever deals testPiece
+func newTestGame(height, width int) Game {
+	return newGame(height, width, []Piece{testPiece}, NewBagRandomizer(0, 1, 1), DefaultRules())
+}
 
 func TestIsInBounds(t *testing.T) {
-	g := NewGame(10, 10, testPiece)
+	g := newTestGame(10, 10)
 	cases := []struct {
 		name string
 		vec  Vector
@@ -34,10 +40,10 @@ func TestIsInBounds(t *testing.T) {
 func TestGetBoard(t *testing.T) {
 	width := 10
 	height := 15
-	g := NewGame(height, width, testPiece)
+	g := newTestGame(height, width)
 	b := g.Board()
 
-	expectedPiecePos := Vector{x: int(width / 2), y: 0}
+	expectedPiecePos := Vector{x: (width - 1) / 2, y: 0}
 
 	for y := range b {
 		for x := range b[y] {
@@ -60,3 +66,228 @@ func TestGetBoard(t *testing.T) {
 		}
 	}
 }
+
+func TestSpawnColumns(t *testing.T) {
+	// Where the guideline spawns each piece on a 10 wide board, leftmost and rightmost block
+	cases := []struct {
+		piece       string
+		left, right int
+	}{
+		{"I", 3, 6},
+		{"O", 4, 5},
+		{"J", 3, 5},
+		{"L", 3, 5},
+		{"S", 3, 5},
+		{"T", 3, 5},
+		{"Z", 3, 5},
+	}
+
+	for _, c := range cases {
+		t.Run(c.piece, func(t *testing.T) {
+			g, err := NewGameFrom(20, 10, 0, DefaultRules(), Setup{Sequence: []string{c.piece}, Only: true})
+			if err != nil {
+				t.Fatalf("Couldn't set up game: %v", err)
+			}
+
+			left, right := 10, -1
+			for _, row := range g.Board() {
+				for x, color := range row {
+					if color != 0 {
+						left, right = min(left, x), max(right, x)
+					}
+				}
+			}
+			if left != c.left || right != c.right {
+				t.Errorf("%v spawned in columns %v-%v, expected %v-%v", c.piece, left, right, c.left, c.right)
+			}
+		})
+	}
+}
+
+// Swap the active piece for one of Pieces, at pos in orientation rot
+func placePiece(g *Game, idx int, rot Rotation, pos Vector) {
+	g.piece = Pieces[idx]
+	g.rot = rot
+	g.pos = pos
+}
+
+func TestRotateFullCircle(t *testing.T) {
+	for idx, p := range Pieces {
+		for _, clockwise := range []bool{true, false} {
+			g := NewGame(20, 10, 0, DefaultRules())
+			placePiece(&g, idx, Rotation0, Vector{3, 8})
+
+			for i := 0; i < 4; i++ {
+				if !g.rotateIfPossible(clockwise) {
+					t.Fatalf("%v couldn't rotate in an empty board", p.name)
+				}
+			}
+
+			if g.rot != Rotation0 || g.pos != (Vector{3, 8}) {
+				t.Errorf("%v didn't end up where it started after 4 turns. rot: %v, pos: %v", p.name, g.rot, g.pos)
+			}
+		}
+	}
+}
+
+func TestWallKicks(t *testing.T) {
+	cases := []struct {
+		name      string
+		piece     int
+		rot       Rotation
+		pos       Vector
+		clockwise bool
+		filled    func(x, y int) bool
+		rotated   bool
+		endRot    Rotation
+		endPos    Vector
+	}{
+		{
+			name: "I kicks off the left wall", piece: PieceI,
+			rot: RotationL, pos: Vector{-1, 5}, clockwise: true,
+			rotated: true, endRot: Rotation0, endPos: Vector{0, 5},
+		},
+		{
+			name: "T kicks up off the floor", piece: PieceT,
+			rot: Rotation0, pos: Vector{4, 18}, clockwise: true,
+			rotated: true, endRot: RotationR, endPos: Vector{3, 17},
+		},
+		{
+			name: "I stuck in a well", piece: PieceI,
+			rot: RotationR, pos: Vector{-2, 16}, clockwise: false,
+			filled:  func(x, y int) bool { return x > 0 && y >= 10 },
+			rotated: false, endRot: RotationR, endPos: Vector{-2, 16},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			g := NewGame(20, 10, 0, DefaultRules())
+			if c.filled != nil {
+				for y := range g.board {
+					for x := range g.board[y] {
+						if c.filled(x, y) {
+							g.board[y][x] = 1
+						}
+					}
+				}
+			}
+			placePiece(&g, c.piece, c.rot, c.pos)
+
+			if rotated := g.rotateIfPossible(c.clockwise); rotated != c.rotated {
+				t.Errorf("Expected rotated to be %v but was %v", c.rotated, rotated)
+			}
+			if g.rot != c.endRot || g.pos != c.endPos {
+				t.Errorf("Expected rot %v at %v, got rot %v at %v", c.endRot, c.endPos, g.rot, g.pos)
+			}
+		})
+	}
+}
+
+func TestHold(t *testing.T) {
+	g := NewGame(20, 10, 0, DefaultRules())
+	first := g.piece
+
+	g.Act(ActionHold)
+	if held, ok := g.Hold(); !ok || held.name != first.name {
+		t.Fatalf("Expected %v to be held", first.name)
+	}
+	second := g.piece
+
+	// Can't hold twice before the piece locks
+	g.Act(ActionHold)
+	if g.piece.name != second.name {
+		t.Errorf("Held twice without locking a piece")
+	}
+
+	g.Act(ActionDrop)
+	g.Act(ActionHold)
+	if g.piece.name != first.name || g.rot != Rotation0 {
+		t.Errorf("Expected held %v to come back in its spawn state, got %v", first.name, g.piece.name)
+	}
+	if g.pos.y != -first.yOffset() {
+		t.Errorf("Held piece didn't come back at the spawn position: %v", g.pos)
+	}
+}
+
+func TestNextQueue(t *testing.T) {
+	rules := DefaultRules()
+	g := NewGame(20, 10, 3, rules)
+
+	// The randomizer on its own should deal the same sequence as the game
+	r := NewRandomizer(rules.Randomizer, 3, len(Pieces))
+	if first := Pieces[r.Next()]; first.name != g.piece.name {
+		t.Fatalf("Expected the first piece to be %v, got %v", first.name, g.piece.name)
+	}
+
+	for i := 0; i < 20; i++ {
+		next := g.Next(10)
+		if len(next) != rules.Previews {
+			t.Fatalf("Expected Next to be capped at %v pieces, got %v", rules.Previews, len(next))
+		}
+		if expected := Pieces[r.Next()]; next[0].name != expected.name {
+			t.Fatalf("Piece %v: expected %v next, got %v", i, expected.name, next[0].name)
+		}
+
+		g.Act(ActionDrop)
+		if g.GameOver {
+			break
+		}
+		if g.piece.name != next[0].name {
+			t.Fatalf("Expected %v to spawn after the drop, got %v", next[0].name, g.piece.name)
+		}
+	}
+}
+
+func TestGhost(t *testing.T) {
+	g := newTestGame(15, 10)
+	// Something for the piece to land on in its column
+	g.board[10][4] = 1
+
+	ghost := g.Ghost()
+	for y := range ghost {
+		for x := range ghost[y] {
+			if x == 4 && y == 9 {
+				if ghost[y][x] != testPiece.color {
+					t.Errorf("Expected the ghost to be on top of the filled block")
+				}
+			} else if ghost[y][x] != 0 {
+				t.Errorf("Unexpected ghost block at x: %v, y: %v", x, y)
+			}
+		}
+	}
+
+	g.Act(ActionDrop)
+	if g.board[9][4] != testPiece.color {
+		t.Errorf("Dropped piece didn't lock where the ghost was")
+	}
+}
+
+func TestClone(t *testing.T) {
+	for _, r := range []RandomizerType{Randomizer7Bag, Randomizer14Bag, RandomizerHistory, RandomizerUniform} {
+		t.Run(r.String(), func(t *testing.T) {
+			rules := DefaultRules()
+			rules.Randomizer = r
+			g := NewGame(20, 10, 3, rules)
+			playRandomly(&g, 3, 300)
+			g.QueueGarbage(1, 0)
+
+			before := g.Replay()
+			board := g.Board()
+			c := g.Clone()
+			playRandomly(&c, 4, 600)
+
+			if !reflect.DeepEqual(g.Board(), board) || !reflect.DeepEqual(g.Replay(), before) {
+				t.Fatalf("Playing the clone changed the original")
+			}
+
+			// Both deal the same pieces from here on
+			c = g.Clone()
+			playRandomly(&g, 5, 600)
+			playRandomly(&c, 5, 600)
+			if !reflect.DeepEqual(g.Board(), c.Board()) || !reflect.DeepEqual(g.Next(5), c.Next(5)) {
+				t.Errorf("Clone played out differently from the original")
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package tetris
+
+// Some rows of garbage, all with the same hole
+type Garbage struct {
+	Lines int
+	Hole  int // Column left empty
+}
+
+// Push lines of gray garbage up from the bottom of the board right now, with a hole in holeColumn.
+// A falling piece in the way gets pushed up with the stack, and the game tops out if the stack or
+// the piece is pushed off the top (unless Rules.NoTopOut)
+func (g *Game) AddGarbage(lines, holeColumn int) {
+	if g.GameOver || lines <= 0 {
+		return
+	}
+
+	gb := Garbage{Lines: lines, Hole: holeColumn}
+	g.recordGarbage(gb, false)
+	g.addGarbage(gb)
+}
+
+// Add garbage that comes up the next time a piece locks without clearing lines, like most versus games do.
+// Until then, line clears can cancel it
+func (g *Game) QueueGarbage(lines, holeColumn int) {
+	if g.GameOver || lines <= 0 {
+		return
+	}
+
+	gb := Garbage{Lines: lines, Hole: holeColumn}
+	g.recordGarbage(gb, true)
+	g.garbage = append(g.garbage, gb)
+}
+
+// Lines of queued garbage waiting for the next lock
+func (g Game) PendingGarbage() int {
+	total := 0
+	for _, gb := range g.garbage {
+		total += gb.Lines
+	}
+	return total
+}
+
+// Bring up everything queued, oldest first
+func (g *Game) applyGarbage() {
+	queued := g.garbage
+	g.garbage = nil
+
+	for _, gb := range queued {
+		if g.GameOver {
+			return
+		}
+		g.addGarbage(gb)
+	}
+}
+
+func (g *Game) addGarbage(gb Garbage) {
+	lines := min(gb.Lines, g.height)
+	hole := min(max(gb.Hole, 0), g.width-1)
+
+	// Blocks in the rows about to go off the top are lost, which ends the game
+	overflow := false
+	for y := 0; y < lines; y++ {
+		for _, c := range g.board[y] {
+			overflow = overflow || c > 0
+		}
+	}
+
+	for y := 0; y < g.height-lines; y++ {
+		copy(g.board[y], g.board[y+lines])
+	}
+	for y := g.height - lines; y < g.height; y++ {
+		for x := range g.board[y] {
+			g.board[y][x] = int(ColorGray)
+		}
+		g.board[y][hole] = 0
+	}
+	g.emit(GarbageReceived{Lines: lines})
+
+	if overflow {
+		g.overflow()
+		return
+	}
+	if g.phase != phaseFalling {
+		return
+	}
+
+	// The piece rides up with the stack, as far as it has to
+	g.lock.lowest -= lines
+	for !g.fits(g.shape(), g.pos) {
+		g.pos.y--
+		if g.pos.y+g.top() < 0 {
+			g.overflow()
+			return
+		}
+	}
+}
+
+// Row of the active piece's highest block, relative to g.pos
+func (g Game) top() int {
+	top := g.piece.size
+	for _, v := range g.shape() {
+		top = min(top, v.y)
+	}
+	return top
+}
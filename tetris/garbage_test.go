@@ -0,0 +1,161 @@
+package tetris
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAddGarbage(t *testing.T) {
+	g := newTestGame(5, 4)
+	g.board[4][0] = 1
+	g.Events()
+
+	g.AddGarbage(2, 1)
+
+	gray := int(ColorGray)
+	expected := [][]int{
+		{0, 0, 0, 0},
+		{0, 0, 0, 0},
+		{1, 0, 0, 0},
+		{gray, 0, gray, gray},
+		{gray, 0, gray, gray},
+	}
+	if !reflect.DeepEqual(g.board, expected) {
+		t.Errorf("Expected board %v after garbage, got %v", expected, g.board)
+	}
+	if types := eventTypes(g.Events()); !reflect.DeepEqual(types, []string{"GarbageReceived"}) {
+		t.Errorf("Expected a GarbageReceived event, got %v", types)
+	}
+}
+
+func TestGarbagePushesPiece(t *testing.T) {
+	g := newTestGame(5, 4)
+	g.pos = Vector{0, 4}
+
+	g.AddGarbage(2, 3)
+	if g.GameOver || g.pos != (Vector{0, 2}) {
+		t.Errorf("Piece should have been pushed up to %v, it's at %v (game over: %v)", Vector{0, 2}, g.pos, g.GameOver)
+	}
+
+	// Standing on the hole, it doesn't need to move
+	g.pos = Vector{3, 4}
+	g.AddGarbage(1, 3)
+	if g.pos != (Vector{3, 4}) {
+		t.Errorf("Piece over the hole shouldn't move, it's at %v", g.pos)
+	}
+}
+
+func TestGarbageTopOut(t *testing.T) {
+	cases := []struct {
+		name  string
+		setup func(g *Game)
+	}{
+		{"Stack pushed off", func(g *Game) { g.board[1][2] = 1 }},
+		{"Piece pushed off", func(g *Game) { g.pos = Vector{0, 3} }},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			g := newTestGame(5, 4)
+			c.setup(&g)
+			g.Events()
+
+			// Fills the whole board but the hole
+			g.AddGarbage(5, 3)
+			if !g.GameOver {
+				t.Errorf("Game should have topped out")
+			}
+			if types := eventTypes(g.Events()); !reflect.DeepEqual(types, []string{"GarbageReceived", "TopOut"}) {
+				t.Errorf("Expected garbage then a top out, got %v", types)
+			}
+			// Nothing's left hanging off the top to draw
+			g.Board()
+			g.Clone().Board()
+		})
+	}
+}
+
+func TestGarbageTopOutOneAtATime(t *testing.T) {
+	g := NewGame(20, 10, 0, DefaultRules())
+	for i := 0; i < 20 && !g.GameOver; i++ {
+		g.AddGarbage(1, 0)
+	}
+	if !g.GameOver {
+		t.Fatalf("Game should have topped out")
+	}
+	if _, ok := g.Active(); ok {
+		t.Errorf("Topped out game still has an active piece")
+	}
+	g.Board()
+	g.Clone().Board()
+}
+
+func TestQueuedGarbage(t *testing.T) {
+	g := NewGame(20, 10, 0, DefaultRules())
+	g.QueueGarbage(2, 0)
+	g.QueueGarbage(1, 9)
+
+	if g.PendingGarbage() != 3 || !g.boardEmpty() {
+		t.Fatalf("Queued garbage should wait for a lock, %v lines pending", g.PendingGarbage())
+	}
+
+	g.Events()
+	g.Act(ActionDrop)
+	if g.PendingGarbage() != 0 {
+		t.Errorf("Garbage still pending after a lock")
+	}
+	// The oldest garbage comes up first, so it ends up on top
+	if g.board[17][0] != 0 || g.board[18][0] != 0 || g.board[19][9] != 0 || g.board[19][0] != int(ColorGray) {
+		t.Errorf("Garbage came up in the wrong order or with the wrong holes")
+	}
+
+	types := eventTypes(g.Events())
+	expected := []string{"PieceLocked", "GarbageReceived", "GarbageReceived", "PieceSpawned"}
+	if !reflect.DeepEqual(types, expected) {
+		t.Errorf("Expected events %v, got %v", expected, types)
+	}
+}
+
+func TestQueuedGarbageUnderLockedPiece(t *testing.T) {
+	// A tall column in the corner for the piece to land on, which garbage then pushes right up to the top
+	board := NewBoard(20, 10)
+	for y := 3; y < 20; y++ {
+		board[y][0] = int(ColorGray)
+	}
+	g, err := NewGameFrom(20, 10, 0, DefaultRules(), Setup{Board: board, Sequence: []string{"O", "T"}, Only: true})
+	if err != nil {
+		t.Fatalf("Couldn't set up game: %v", err)
+	}
+
+	g.QueueGarbage(1, 9)
+	g.Step(0, append(times(4, ActionLeft), ActionDrop))
+
+	// The O is part of the stack by then, and the T still has room to spawn
+	if g.GameOver {
+		t.Fatalf("Topped out with room to spawn the next piece")
+	}
+	if a, ok := g.Active(); !ok || a.Piece.Name() != "T" {
+		t.Errorf("Expected the T to spawn, got %v", a.Piece.Name())
+	}
+	if g.board[0][0] != Pieces[PieceO].color || g.board[1][1] != Pieces[PieceO].color {
+		t.Errorf("The O didn't go up with the garbage")
+	}
+	g.Board()
+}
+
+func TestGarbageReplay(t *testing.T) {
+	g := NewGame(20, 10, 4, DefaultRules())
+	playRandomly(&g, 4, 300)
+	g.AddGarbage(2, 3)
+	playRandomly(&g, 5, 300)
+	g.QueueGarbage(3, 7)
+	playRandomly(&g, 6, 600)
+
+	r := g.Replay()
+	played, _ := r.NewGame()
+	r.Advance(&played, r.Frames+1)
+
+	if !reflect.DeepEqual(played.Board(), g.Board()) || played.Score() != g.Score() {
+		t.Errorf("Replay with garbage played out differently")
+	}
+}
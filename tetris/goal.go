@@ -0,0 +1,109 @@
+package tetris
+
+// Something to do to finish a game, like "T-spin double" or "perfect clear within 10 pieces".
+// Every part that's set has to be done. A goal that's only a piece or time limit is won by
+// lasting that long. The zero Goal is a normal game that runs until it tops out
+type Goal struct {
+	Lines int // Clear at least this many lines in total
+	// Make a spin of at least this type that clears at least SpinLines lines
+	Spin      SpinType
+	SpinLines int
+	// Make a perfect clear
+	PerfectClear bool
+	// Do everything within this many pieces, 0 for no limit
+	Pieces int
+	// Do everything within this many frames, 0 for no limit
+	Frames int
+}
+
+// How a game with a goal ended
+type Outcome int
+
+const (
+	OutcomePlaying Outcome = iota
+	OutcomeWon
+	OutcomeLost
+)
+
+func (o Outcome) String() string {
+	switch o {
+	case OutcomePlaying:
+		return "playing"
+	case OutcomeWon:
+		return "won"
+	case OutcomeLost:
+		return "lost"
+	default:
+		return "invalid Outcome"
+	}
+}
+
+// Parts of the goal done so far
+type goalProgress struct {
+	placed       int // Pieces locked
+	spin         bool
+	perfectClear bool
+}
+
+func (g Goal) active() bool {
+	return g != Goal{}
+}
+
+// Is there anything to do, besides last?
+func (g Goal) target() bool {
+	return g.Lines > 0 || g.Spin != SpinNone || g.PerfectClear
+}
+
+func (g Game) Outcome() Outcome {
+	return g.outcome
+}
+
+// Pieces locked so far
+func (g Game) Placed() int {
+	return g.progress.placed
+}
+
+// Count a locked piece towards the goal, and finish the game if it's done or can't be done anymore
+func (g *Game) checkGoal(c Clear) {
+	goal := g.rules.Goal
+	g.progress.placed++
+	if !goal.active() {
+		return
+	}
+
+	if goal.Spin != SpinNone && c.Spin >= goal.Spin && c.Lines() >= goal.SpinLines {
+		g.progress.spin = true
+	}
+	if c.PerfectClear {
+		g.progress.perfectClear = true
+	}
+
+	done := g.lines >= goal.Lines &&
+		(goal.Spin == SpinNone || g.progress.spin) &&
+		(!goal.PerfectClear || g.progress.perfectClear)
+	outOfPieces := goal.Pieces > 0 && g.progress.placed >= goal.Pieces
+
+	switch {
+	case goal.target() && done:
+		g.finish(OutcomeWon)
+	case outOfPieces && !goal.target():
+		g.finish(OutcomeWon)
+	case outOfPieces:
+		g.finish(OutcomeLost)
+	}
+}
+
+// Goal.Frames ran out
+func (g *Game) timeUp() {
+	if g.rules.Goal.target() {
+		g.finish(OutcomeLost)
+	} else {
+		g.finish(OutcomeWon)
+	}
+}
+
+func (g *Game) finish(o Outcome) {
+	g.outcome = o
+	g.GameOver = true
+	g.emit(Finished{Outcome: o})
+}
@@ -0,0 +1,103 @@
+package tetris
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+// Repeat an action n times
+func times(n int, a Action) []Action {
+	actions := make([]Action, n)
+	for i := range actions {
+		actions[i] = a
+	}
+	return actions
+}
+
+func TestGoals(t *testing.T) {
+	tsd := []string{
+		"##........",
+		"#...######",
+		"##.#######",
+	}
+	// Turn the T so it points right, slide it over the slot and soft drop it in, then spin it down
+	tsdInputs := slices.Concat([]Action{ActionRotate}, times(2, ActionLeft), times(20, ActionDown), []Action{ActionRotate, ActionDrop})
+
+	pc := []string{
+		"######....",
+		"######....",
+	}
+	pcInputs := slices.Concat(
+		times(3, ActionRight), []Action{ActionDrop},
+		times(2, ActionRotate), times(4, ActionRight), []Action{ActionDrop},
+	)
+
+	cases := []struct {
+		name    string
+		board   []string
+		seq     []string
+		goal    Goal
+		inputs  []Action
+		outcome Outcome
+	}{
+		{"T-spin double", tsd, []string{"T"}, Goal{Spin: SpinFull, SpinLines: 2, Pieces: 1}, tsdInputs, OutcomeWon},
+		{"No spin", tsd, []string{"T"}, Goal{Spin: SpinFull, SpinLines: 2, Pieces: 1}, []Action{ActionDrop}, OutcomeLost},
+		{"Not enough lines", tsd, []string{"T", "O"}, Goal{Spin: SpinFull, SpinLines: 3}, tsdInputs, OutcomePlaying},
+		{"Perfect clear", pc, []string{"J", "J"}, Goal{PerfectClear: true, Pieces: 2}, pcInputs, OutcomeWon},
+		{"Lines", pc, []string{"J", "J", "O"}, Goal{Lines: 2}, pcInputs, OutcomeWon},
+		{"Out of pieces", pc, []string{"J"}, Goal{PerfectClear: true}, []Action{ActionDrop}, OutcomeLost},
+		{"Still going", pc, []string{"J", "J", "O"}, Goal{PerfectClear: true}, []Action{ActionDrop}, OutcomePlaying},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			board, err := ParseBoard(20, 10, c.board...)
+			if err != nil {
+				t.Fatalf("Couldn't parse board: %v", err)
+			}
+			rules := DefaultRules()
+			rules.Goal = c.goal
+
+			g, err := NewGameFrom(20, 10, 0, rules, Setup{Board: board, Sequence: c.seq, Only: true})
+			if err != nil {
+				t.Fatalf("Couldn't set up game: %v", err)
+			}
+
+			g.Step(0, c.inputs)
+			if g.Outcome() != c.outcome {
+				t.Errorf("Game ended up %v, expected %v", g.Outcome(), c.outcome)
+			}
+			if g.GameOver != (c.outcome != OutcomePlaying) {
+				t.Errorf("Game over is %v for a game that's %v", g.GameOver, g.Outcome())
+			}
+		})
+	}
+}
+
+func TestSequenceRunsOut(t *testing.T) {
+	g, err := NewGameFrom(20, 10, 0, DefaultRules(), Setup{Sequence: []string{"O", "I"}, Only: true})
+	if err != nil {
+		t.Fatalf("Couldn't set up game: %v", err)
+	}
+
+	if names := pieceNames(g.Next(5)); !reflect.DeepEqual(names, []string{"I"}) {
+		t.Errorf("Expected only the I to be next, got %v", names)
+	}
+
+	g.Step(0, []Action{ActionDrop})
+	if types := eventTypes(g.Step(0, []Action{ActionDrop})); !reflect.DeepEqual(types, []string{"PieceLocked", "Finished"}) {
+		t.Errorf("Expected the game to finish when the pieces ran out, got %v", types)
+	}
+	if g.Outcome() != OutcomeLost {
+		t.Errorf("Running out of pieces should lose, the game %v", g.Outcome())
+	}
+}
+
+func pieceNames(pieces []Piece) []string {
+	names := make([]string, len(pieces))
+	for i, p := range pieces {
+		names[i] = p.name
+	}
+	return names
+}
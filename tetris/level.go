@@ -0,0 +1,57 @@
+package tetris
+
+// How the level goes up as lines are cleared
+type LevelRule int
+
+const (
+	// Level up every Rules.LinesPerLevel lines
+	LevelFixedGoal LevelRule = iota
+	// Level n takes 5*n lines to clear, the guideline's variable goal
+	LevelVariableGoal
+	// Stay on the starting level forever
+	LevelNone
+)
+
+// Anything at or above this drops pieces straight to the bottom
+const Gravity20G = 20.0
+
+// Cells per frame for each level starting at 1, from the guideline's
+// (0.8 - (level-1) * 0.007)^(level-1) seconds per row. Levels past the end use the last entry
+var GravityTable = []float64{
+	0.01667, 0.02102, 0.02698, 0.03526, 0.04692,
+	0.06361, 0.08787, 0.12370, 0.17753, 0.25980,
+	0.38781, 0.59065, 0.91811, 1.45696, 2.36118,
+	3.90910, 6.61354, 11.43794, Gravity20G,
+}
+
+func (g Game) Level() int {
+	return g.level
+}
+
+// Total lines cleared
+func (g Game) Lines() int {
+	return g.lines
+}
+
+// Cells per frame at the current level
+func (g Game) Gravity() float64 {
+	idx := min(max(g.level, 1), len(GravityTable)) - 1
+	return GravityTable[idx]
+}
+
+// Count cleared lines towards the next level
+func (g *Game) addLines(n int) {
+	g.lines += n
+
+	switch g.rules.LevelRule {
+	case LevelFixedGoal:
+		if g.rules.LinesPerLevel > 0 {
+			g.level = max(g.level, max(g.rules.StartLevel, 1)+g.lines/g.rules.LinesPerLevel)
+		}
+	case LevelVariableGoal:
+		// Level n needs 5 * (1 + 2 + ... + n) lines in total to get past
+		for g.lines >= 5*g.level*(g.level+1)/2 {
+			g.level++
+		}
+	}
+}
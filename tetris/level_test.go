@@ -0,0 +1,92 @@
+package tetris
+
+import "testing"
+
+func TestLevelRules(t *testing.T) {
+	cases := []struct {
+		name  string
+		rule  LevelRule
+		lines []int // Lines cleared at a time
+		level int
+	}{
+		{"Fixed goal, not there yet", LevelFixedGoal, []int{4, 4, 1}, 1},
+		{"Fixed goal", LevelFixedGoal, []int{4, 4, 2}, 2},
+		{"Fixed goal, several levels", LevelFixedGoal, []int{4, 4, 4, 4, 4, 4, 4, 4}, 4},
+		{"Variable goal", LevelVariableGoal, []int{4, 1}, 2},
+		{"Variable goal, level 2 takes 10 more", LevelVariableGoal, []int{4, 4, 4, 2}, 2},
+		{"Variable goal, level 3", LevelVariableGoal, []int{4, 4, 4, 3}, 3},
+		{"No levels", LevelNone, []int{4, 4, 4, 4, 4, 4}, 1},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			rules := DefaultRules()
+			rules.LevelRule = c.rule
+			g := NewGame(20, 10, 0, rules)
+
+			for _, n := range c.lines {
+				g.addLines(n)
+			}
+			if g.Level() != c.level {
+				t.Errorf("Expected level %v, got %v", c.level, g.Level())
+			}
+		})
+	}
+}
+
+func TestGravity(t *testing.T) {
+	cases := []struct {
+		level  int
+		frames int // Frames it should take to fall a row
+		rows   int // -1 for all the way down
+	}{
+		{1, 60, 1},
+		{10, 4, 1},
+		{15, 1, 2},
+		{19, 1, -1},
+		{30, 1, -1},
+	}
+
+	for _, c := range cases {
+		g := NewGame(20, 10, 0, DefaultRules())
+		g.level = c.level
+		start := g.pos
+		if c.rows < 0 {
+			c.rows = g.landingPos().y - start.y
+		}
+
+		g.Step(c.frames-1, nil)
+		if g.pos != start {
+			t.Errorf("Level %v: piece fell before %v frames", c.level, c.frames)
+		}
+
+		g.Step(1, nil)
+		if rows := g.pos.y - start.y; rows != c.rows {
+			t.Errorf("Level %v: expected to fall %v rows after %v frames, fell %v", c.level, c.rows, c.frames, rows)
+		}
+	}
+}
+
+func TestEntryDelay(t *testing.T) {
+	rules := DefaultRules()
+	rules.ARE = 10
+	rules.LineClearDelay = 20
+
+	g := newGame(20, 10, []Piece{testPiece}, NewBagRandomizer(0, 1, 1), rules)
+	fillRow(&g, 19, g.pos.x)
+
+	g.Step(0, []Action{ActionDrop})
+	if g.phase != phaseEntry {
+		t.Fatalf("Expected to be waiting for the next piece after a lock")
+	}
+
+	g.Step(rules.ARE+rules.LineClearDelay-1, nil)
+	if g.phase != phaseEntry {
+		t.Fatalf("Next piece came in before ARE and the line clear delay were up")
+	}
+
+	events := g.Step(1, nil)
+	if g.phase != phaseFalling || len(events) == 0 {
+		t.Errorf("Next piece didn't come in after ARE and the line clear delay")
+	}
+}
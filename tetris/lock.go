@@ -0,0 +1,65 @@
+package tetris
+
+// The engine runs at a fixed 60 frames a second, delays in Rules are counted in frames
+const FramesPerSecond = 60
+
+// Under LockResetMove a piece can only push its lock back this many times
+// before it has to fall lower than it's been
+const MaxLockResets = 15
+
+// What pushes back the lock delay once a piece is sitting on the stack
+type LockResetPolicy int
+
+const (
+	// Moving or rotating restarts the lock delay, up to MaxLockResets times ("infinity")
+	LockResetMove LockResetPolicy = iota
+	// Only falling a row restarts the lock delay
+	LockResetStep
+)
+
+func (p LockResetPolicy) String() string {
+	switch p {
+	case LockResetMove:
+		return "move reset"
+	case LockResetStep:
+		return "step reset"
+	default:
+		return "invalid LockResetPolicy"
+	}
+}
+
+type lockState struct {
+	frames int // Frames spent on the ground since the last reset
+	resets int // Move resets used since the piece was last at its lowest
+	lowest int // Lowest row the piece has reached
+}
+
+// Is the active piece resting on the stack or the floor?
+func (g Game) grounded() bool {
+	return !g.fits(g.shape(), g.pos.add(Vector{0, 1}))
+}
+
+// Call after the piece falls a row. Every policy restarts the delay on a step, and
+// getting lower than before gives the piece its move resets back
+func (g *Game) stepped() {
+	g.lock.frames = 0
+	if g.pos.y > g.lock.lowest {
+		g.lock.lowest = g.pos.y
+		g.lock.resets = 0
+	}
+}
+
+// Call after a move or rotation, ok being whether it actually happened
+func (g *Game) moved(ok bool) {
+	if !ok || g.rules.LockReset != LockResetMove {
+		return
+	}
+
+	if g.lock.resets < MaxLockResets {
+		// Resets are only used up by moving while on the ground
+		if g.lock.frames > 0 || g.grounded() {
+			g.lock.resets++
+		}
+		g.lock.frames = 0
+	}
+}
@@ -0,0 +1,89 @@
+package tetris
+
+import "testing"
+
+// A test piece sitting on the floor of a fresh game
+func groundedGame(policy LockResetPolicy) Game {
+	rules := DefaultRules()
+	rules.LockReset = policy
+	g := newGame(10, 10, []Piece{testPiece}, NewBagRandomizer(0, 1, 1), rules)
+	g.pos = g.landingPos()
+	return g
+}
+
+func TestLockDelay(t *testing.T) {
+	g := groundedGame(LockResetMove)
+	start := g.pos
+
+	g.Step(1, nil)
+	if g.board[start.y][start.x] != 0 {
+		t.Fatalf("Piece locked as soon as it couldn't fall")
+	}
+
+	g.Step(g.rules.LockDelay-2, nil)
+	if g.board[start.y][start.x] != 0 {
+		t.Fatalf("Piece locked before the lock delay was up")
+	}
+
+	g.Step(1, nil)
+	if g.board[start.y][start.x] != testPiece.color {
+		t.Errorf("Piece didn't lock once the lock delay was up")
+	}
+}
+
+func TestNoLockDelay(t *testing.T) {
+	g := groundedGame(LockResetMove)
+	g.rules.LockDelay = 0
+	start := g.pos
+
+	g.Step(1, nil)
+	if g.board[start.y][start.x] != testPiece.color {
+		t.Errorf("Piece should lock right away without a lock delay")
+	}
+}
+
+// The grounded piece is on the floor, anything else means it locked and a new one spawned
+func locked(g Game) bool {
+	return g.pos.y != g.height-1
+}
+
+// Tick until just before the lock delay runs out, then slide the piece over
+func wiggle(g *Game) {
+	g.Step(g.rules.LockDelay-1, nil)
+	if g.pos.x > 0 {
+		g.Act(ActionLeft)
+	} else {
+		g.Act(ActionRight)
+	}
+}
+
+func TestLockResets(t *testing.T) {
+	cases := []struct {
+		name   string
+		policy LockResetPolicy
+		resets int // How many wiggles should push back the lock
+	}{
+		{"Move reset", LockResetMove, MaxLockResets},
+		{"Step reset", LockResetStep, 0},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			g := groundedGame(c.policy)
+
+			for i := 0; i < c.resets; i++ {
+				wiggle(&g)
+				if locked(g) {
+					t.Fatalf("Piece locked after %v resets, expected %v", i, c.resets)
+				}
+			}
+
+			// This one shouldn't buy any more time
+			wiggle(&g)
+			g.Step(1, nil)
+			if !locked(g) {
+				t.Errorf("Piece didn't lock after using up its %v resets", c.resets)
+			}
+		})
+	}
+}
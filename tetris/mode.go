@@ -0,0 +1,63 @@
+package tetris
+
+// A named way to play, like Sprint or Zen. Everything that makes a mode what it is lives in its Rules
+type Mode struct {
+	Name        string
+	Description string
+	Rules       Rules
+}
+
+var (
+	ModeSprint = Mode{
+		Name:        "Sprint",
+		Description: "Clear 40 lines as fast as you can",
+		// Same speed the whole way, so times only depend on the player
+		Rules: modeRules(func(r *Rules) {
+			r.Goal = Goal{Lines: 40}
+			r.LevelRule = LevelNone
+		}),
+	}
+	ModeUltra = Mode{
+		Name:        "Ultra",
+		Description: "Score as much as you can in 2 minutes",
+		Rules:       modeRules(func(r *Rules) { r.Goal = Goal{Frames: 2 * 60 * FramesPerSecond} }),
+	}
+	ModeMarathon = Mode{
+		Name:        "Marathon",
+		Description: "Clear 150 lines as the levels speed up",
+		Rules: modeRules(func(r *Rules) {
+			r.Goal = Goal{Lines: 150}
+			r.LevelRule = LevelFixedGoal
+			r.LinesPerLevel = 10
+		}),
+	}
+	ModeZen = Mode{
+		Name:        "Zen",
+		Description: "No speed up, no topping out",
+		Rules: modeRules(func(r *Rules) {
+			r.LevelRule = LevelNone
+			r.NoTopOut = true
+		}),
+	}
+)
+
+func modeRules(change func(r *Rules)) Rules {
+	r := DefaultRules()
+	change(&r)
+	return r
+}
+
+// The standard modes, in the order they should be shown
+func Modes() []Mode {
+	return []Mode{ModeSprint, ModeUltra, ModeMarathon, ModeZen}
+}
+
+// The standard mode played with these rules, if there is one
+func ModeOf(rules Rules) (Mode, bool) {
+	for _, m := range Modes() {
+		if m.Rules == rules {
+			return m, true
+		}
+	}
+	return Mode{}, false
+}
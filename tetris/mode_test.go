@@ -0,0 +1,87 @@
+package tetris
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestModeOf(t *testing.T) {
+	for _, m := range Modes() {
+		found, ok := ModeOf(m.Rules)
+		if !ok || found.Name != m.Name {
+			t.Errorf("Expected %v's rules to be %v, got %v", m.Name, m.Name, found.Name)
+		}
+	}
+
+	if m, ok := ModeOf(DefaultRules()); ok {
+		t.Errorf("Default rules shouldn't be a mode, got %v", m.Name)
+	}
+}
+
+func TestTimeLimit(t *testing.T) {
+	cases := []struct {
+		name    string
+		goal    Goal
+		outcome Outcome
+	}{
+		{"Lasted", Goal{Frames: 100}, OutcomeWon},
+		{"Didn't clear the lines in time", Goal{Lines: 10, Frames: 100}, OutcomeLost},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			rules := DefaultRules()
+			rules.Goal = c.goal
+			g := NewGame(20, 10, 0, rules)
+
+			g.Step(99, nil)
+			if g.GameOver {
+				t.Fatalf("Game ended before the time was up")
+			}
+			events := g.Step(1, nil)
+			if g.Outcome() != c.outcome || !g.GameOver {
+				t.Errorf("Game ended up %v, expected %v", g.Outcome(), c.outcome)
+			}
+			if !slices.Contains(eventTypes(events), "Finished") {
+				t.Errorf("Expected a Finished event when the time ran out, got %v", eventTypes(events))
+			}
+		})
+	}
+}
+
+func TestZenDoesntTopOut(t *testing.T) {
+	g := NewGame(20, 10, 0, ModeZen.Rules)
+
+	var types []string
+	for i := 0; i < 100 && !slices.Contains(types, "BoardReset"); i++ {
+		types = append(types, eventTypes(g.Step(0, []Action{ActionDrop}))...)
+	}
+
+	if !slices.Contains(types, "BoardReset") {
+		t.Fatalf("Board never filled up")
+	}
+	if g.GameOver || slices.Contains(types, "TopOut") {
+		t.Errorf("Zen game shouldn't top out")
+	}
+
+	// The garbage that would have topped out resets the board too
+	g.AddGarbage(20, 0)
+	if g.GameOver {
+		t.Errorf("Zen game shouldn't top out from garbage")
+	}
+}
+
+func TestSprintDoesntLevelUp(t *testing.T) {
+	g := NewGame(20, 10, 0, ModeSprint.Rules)
+	g.lines = 39
+	fillRow(&g, 19, 0)
+	placePiece(&g, PieceI, RotationR, Vector{-2, 5})
+
+	g.Act(ActionDrop)
+	if g.Level() != ModeSprint.Rules.StartLevel {
+		t.Errorf("Expected to stay on level %v, got %v", ModeSprint.Rules.StartLevel, g.Level())
+	}
+	if !g.GameOver || g.Outcome() != OutcomeWon {
+		t.Errorf("Expected 40 lines to finish the sprint")
+	}
+}
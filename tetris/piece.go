@@ -1,79 +1,154 @@
 package tetris
 
-import (
-	"math"
-	"math/rand"
+type Piece struct {
+	name  string
+	color int
+	size  int // Width of the square bounding box the states are turned in
+	// Blocks for each orientation, relative to the top left of the piece's bounding box
+	states [4][]Vector
+	kicks  kickTable
+	spawn  Vector // Nudges the piece from where it would normally spawn
+	spins  bool   // Gets T-spins, by the 3 corner rule (see Game.spin)
+}
+
+// Orientation states, named like SRS does
+type Rotation int
+
+const (
+	Rotation0 Rotation = iota // Spawn state
+	RotationR                 // One turn clockwise
+	Rotation2                 // Two turns
+	RotationL                 // One turn counterclockwise
 )
 
-type Piece struct {
-	shape     []Vector
-	color     int
-	canRotate bool
+func (r Rotation) cw() Rotation {
+	return (r + 1) % 4
+}
+
+func (r Rotation) ccw() Rotation {
+	return (r + 3) % 4
 }
 
-type Color int
+type kick struct {
+	from, to Rotation
+}
 
+// Offsets tried in order when rotating from one orientation to another.
+// Written y-up like the SRS tables everyone copies, so y is flipped when a kick is applied
+type kickTable map[kick][]Vector
+
+var jlstzKicks = kickTable{
+	{Rotation0, RotationR}: {{0, 0}, {-1, 0}, {-1, 1}, {0, -2}, {-1, -2}},
+	{RotationR, Rotation0}: {{0, 0}, {1, 0}, {1, -1}, {0, 2}, {1, 2}},
+	{RotationR, Rotation2}: {{0, 0}, {1, 0}, {1, -1}, {0, 2}, {1, 2}},
+	{Rotation2, RotationR}: {{0, 0}, {-1, 0}, {-1, 1}, {0, -2}, {-1, -2}},
+	{Rotation2, RotationL}: {{0, 0}, {1, 0}, {1, 1}, {0, -2}, {1, -2}},
+	{RotationL, Rotation2}: {{0, 0}, {-1, 0}, {-1, -1}, {0, 2}, {-1, 2}},
+	{RotationL, Rotation0}: {{0, 0}, {-1, 0}, {-1, -1}, {0, 2}, {-1, 2}},
+	{Rotation0, RotationL}: {{0, 0}, {1, 0}, {1, 1}, {0, -2}, {1, -2}},
+}
+
+var iKicks = kickTable{
+	{Rotation0, RotationR}: {{0, 0}, {-2, 0}, {1, 0}, {-2, -1}, {1, 2}},
+	{RotationR, Rotation0}: {{0, 0}, {2, 0}, {-1, 0}, {2, 1}, {-1, -2}},
+	{RotationR, Rotation2}: {{0, 0}, {-1, 0}, {2, 0}, {-1, 2}, {2, -1}},
+	{Rotation2, RotationR}: {{0, 0}, {1, 0}, {-2, 0}, {1, -2}, {-2, 1}},
+	{Rotation2, RotationL}: {{0, 0}, {2, 0}, {-1, 0}, {2, 1}, {-1, -2}},
+	{RotationL, Rotation2}: {{0, 0}, {-2, 0}, {1, 0}, {-2, -1}, {1, 2}},
+	{RotationL, Rotation0}: {{0, 0}, {1, 0}, {-2, 0}, {1, -2}, {-2, 1}},
+	{Rotation0, RotationL}: {{0, 0}, {-1, 0}, {2, 0}, {-1, 2}, {2, -1}},
+}
+
+// Indexes into Pieces
 const (
-	ColorEmpty Color = iota
-	ColorGreen
-	ColorYellow
-	ColorRed
-	ColorPurple
-	ColorOrange
-	ColorBlue
+	PieceO = iota
+	PieceI
+	PieceL
+	PieceJ
+	PieceT
+	PieceZ
+	PieceS
 )
 
-// Color is 0 for empty, or one of the 256 terminal colors
-// Shouldn't this be a uint8 then? nah cause I might also make this
-// a hex color code or change how this is implemented more later so nahhhh
+// The standard tetrominoes, the StandardPieceSet
 var Pieces = []Piece{
-	{ // Block
-		color:     int(ColorYellow),
-		canRotate: false,
-		shape:     []Vector{{0, 0}, {1, 0}, {0, 1}, {1, 1}},
-	},
-	{ // Line
-		color:     int(ColorBlue),
-		canRotate: true,
-		shape:     []Vector{{0, 0}, {0, -1}, {0, 1}, {0, 2}},
-	},
-	{ // L
-		color:     int(ColorGreen),
-		canRotate: true,
-		shape:     []Vector{{0, -1}, {0, 0}, {0, 1}, {-1, 1}},
-	},
-	{ // J
-		color:     int(ColorBlue),
-		canRotate: true,
-		shape:     []Vector{{0, -1}, {0, 0}, {0, 1}, {1, 1}},
-	},
-	{ // T
-		color:     int(ColorPurple),
-		canRotate: true,
-		shape:     []Vector{{0, 0}, {-1, 0}, {1, 0}, {0, 1}},
-	},
-	{ // Z
-		color:     int(ColorRed),
-		canRotate: true,
-		shape:     []Vector{{0, 0}, {-1, 0}, {0, 1}, {1, 1}},
-	},
-	{ // S
-		color:     int(ColorOrange),
-		canRotate: true,
-		shape:     []Vector{{0, 0}, {1, 0}, {0, 1}, {-1, 1}},
-	},
+	newPiece("O", ColorYellow, nil,
+		"##",
+		"##"),
+	newPiece("I", ColorCyan, iKicks,
+		"....",
+		"####",
+		"....",
+		"...."),
+	newPiece("L", ColorGreen, jlstzKicks,
+		"..#",
+		"###",
+		"..."),
+	newPiece("J", ColorBlue, jlstzKicks,
+		"#..",
+		"###",
+		"..."),
+	newPiece("T", ColorPurple, jlstzKicks,
+		".#.",
+		"###",
+		"...").withSpins(),
+	newPiece("Z", ColorRed, jlstzKicks,
+		"##.",
+		".##",
+		"..."),
+	newPiece("S", ColorOrange, jlstzKicks,
+		".##",
+		"##.",
+		"..."),
 }
 
-func RandomPiece() Piece {
-	idx := rand.Intn(len(Pieces))
-	return Pieces[idx]
+// Build a piece from its spawn state drawn in a square box, '#' for blocks.
+// The other states are the box turned clockwise, which is how SRS defines them
+func newPiece(name string, color Color, kicks kickTable, rows ...string) Piece {
+	p := Piece{
+		name:  name,
+		color: int(color),
+		size:  len(rows),
+		kicks: kicks,
+	}
+
+	for y, row := range rows {
+		for x, c := range row {
+			if c == '#' {
+				p.states[Rotation0] = append(p.states[Rotation0], Vector{x, y})
+			}
+		}
+	}
+
+	for r := RotationR; r <= RotationL; r++ {
+		prev := p.states[r-1]
+		p.states[r] = make([]Vector, len(prev))
+		for i, b := range prev {
+			p.states[r][i] = Vector{p.size - 1 - b.y, b.x}
+		}
+	}
+
+	return p
+}
+
+func (p Piece) withSpins() Piece {
+	p.spins = true
+	return p
+}
+
+func (p Piece) Name() string {
+	return p.name
+}
+
+func (p Piece) Color() Color {
+	return Color(p.color)
 }
 
 func (p Piece) yOffset() int {
 	offset := 0
 
-	for _, v := range p.shape {
-		if v.y < offset {
+	for i, v := range p.states[Rotation0] {
+		if i == 0 || v.y < offset {
 			offset = v.y
 		}
 	}
@@ -81,32 +156,31 @@ func (p Piece) yOffset() int {
 	return offset
 }
 
-func (p Piece) copy() Piece {
-	newP := Piece{
-		color:     p.color,
-		canRotate: p.canRotate,
-		shape:     make([]Vector, len(p.shape)),
+// Kicks to try when turning from one orientation to another. Pieces without a
+// kick table (like O) only try turning in place
+func (p Piece) kicksFor(from, to Rotation) []Vector {
+	if k, ok := p.kicks[kick{from, to}]; ok {
+		return k
 	}
-
-	copy(newP.shape, p.shape)
-
-	return newP
+	return []Vector{{0, 0}}
 }
 
-func (p Piece) rotate() Piece {
-	newP := p.copy()
-
-	// idk trig I just copy paste grug
-	ang := math.Pi / 2
-	cos := int(math.Round(math.Cos(ang)))
-	sin := int(math.Round(math.Sin(ang)))
-
-	for i, block := range p.shape {
-		nx := block.y*sin - block.x*cos
-		ny := block.y*cos - block.x*sin
+// The spawn state as a grid just big enough to hold it, for drawing hold/next previews
+func (p Piece) Preview() [][]int {
+	shape := p.states[Rotation0]
+	if len(shape) == 0 {
+		return nil
+	}
 
-		newP.shape[i] = Vector{nx, ny}
+	lo, hi := shape[0], shape[0]
+	for _, v := range shape {
+		lo = Vector{x: min(lo.x, v.x), y: min(lo.y, v.y)}
+		hi = Vector{x: max(hi.x, v.x), y: max(hi.y, v.y)}
 	}
 
-	return newP
+	b := NewBoard(hi.y-lo.y+1, hi.x-lo.x+1)
+	for _, v := range shape {
+		b[v.y-lo.y][v.x-lo.x] = p.color
+	}
+	return b
 }
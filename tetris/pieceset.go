@@ -0,0 +1,214 @@
+package tetris
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Name of the set made of Pieces, the set games use unless Rules.PieceSet says otherwise
+const StandardPieceSet = "standard"
+
+// The pieces a game is played with
+type PieceSet struct {
+	Name   string
+	Pieces []Piece
+}
+
+var pieceSets = map[string]PieceSet{
+	StandardPieceSet: {Name: StandardPieceSet, Pieces: Pieces},
+}
+
+// Make a set available to games by its name, replacing any set already registered under it.
+// Sets should all be registered at startup, before any games are running
+func RegisterPieceSet(set PieceSet) {
+	pieceSets[set.Name] = set
+}
+
+// The registered set with this name. An empty name is the standard set
+func PieceSetNamed(name string) (PieceSet, bool) {
+	if name == "" {
+		name = StandardPieceSet
+	}
+	set, ok := pieceSets[name]
+	return set, ok
+}
+
+// Every registered set's name, sorted
+func PieceSetNames() []string {
+	var names []string
+	for name := range pieceSets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// The pieces from the rules' piece set
+func (r Rules) pieces() ([]Piece, error) {
+	set, ok := PieceSetNamed(r.PieceSet)
+	if !ok {
+		return nil, fmt.Errorf("no piece set named %q", r.PieceSet)
+	}
+	return set.Pieces, nil
+}
+
+/*** PIECE SET FILES ***/
+
+type pieceSetFile struct {
+	Name   string
+	Pieces []pieceFile
+}
+
+type pieceFile struct {
+	Name  string
+	Color Color
+	// Spawn state drawn in a square box, '#' for blocks. The other states are the box turned clockwise
+	Shape []string
+	// All four states (0, R, 2, L), for pieces that don't just turn in their box. Replaces Shape
+	States [][]string
+	// "srs" for the JLSTZ kicks, "srs-i" for the I kicks, "none" for turning in place,
+	// or a table like {"0>R": [[0, 0], [-1, 0]]} written y-up like the SRS tables
+	Kicks json.RawMessage
+	// Moves the spawn position, x to the right and y down
+	Spawn [2]int
+	// Gets T-spins like the T does. Only for pieces in a 3x3 box, since that's what the corners are checked around
+	Spins bool
+}
+
+var namedKicks = map[string]kickTable{
+	"srs":   jlstzKicks,
+	"srs-i": iKicks,
+	"none":  nil,
+}
+
+var rotationNames = map[string]Rotation{"0": Rotation0, "R": RotationR, "2": Rotation2, "L": RotationL}
+
+// Read a piece set from a JSON file, like the ones in the pieces directory
+func ParsePieceSet(data []byte) (PieceSet, error) {
+	var f pieceSetFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return PieceSet{}, err
+	}
+
+	if f.Name == "" {
+		return PieceSet{}, fmt.Errorf("piece set doesn't have a name")
+	}
+	if len(f.Pieces) == 0 {
+		return PieceSet{}, fmt.Errorf("piece set %q doesn't have any pieces", f.Name)
+	}
+
+	set := PieceSet{Name: f.Name}
+	for _, pf := range f.Pieces {
+		p, err := pf.piece()
+		if err != nil {
+			return PieceSet{}, fmt.Errorf("piece %q: %w", pf.Name, err)
+		}
+		if pieceIndex(set.Pieces, p.name) >= 0 {
+			return PieceSet{}, fmt.Errorf("there's more than one piece named %q", p.name)
+		}
+		set.Pieces = append(set.Pieces, p)
+	}
+	return set, nil
+}
+
+func (pf pieceFile) piece() (Piece, error) {
+	if pf.Name == "" {
+		return Piece{}, fmt.Errorf("piece doesn't have a name")
+	}
+	if pf.Color == ColorEmpty {
+		return Piece{}, fmt.Errorf("piece needs a color")
+	}
+
+	kicks, err := parseKicks(pf.Kicks)
+	if err != nil {
+		return Piece{}, err
+	}
+
+	var p Piece
+	switch {
+	case len(pf.States) > 0:
+		if len(pf.States) != 4 {
+			return Piece{}, fmt.Errorf("piece has %v states, expected 4", len(pf.States))
+		}
+		p = Piece{name: pf.Name, color: int(pf.Color), size: len(pf.States[0]), kicks: kicks}
+		for r, rows := range pf.States {
+			if err := checkBox(rows, p.size); err != nil {
+				return Piece{}, err
+			}
+			p.states[r] = newPiece(pf.Name, pf.Color, kicks, rows...).states[Rotation0]
+		}
+	case len(pf.Shape) > 0:
+		if err := checkBox(pf.Shape, len(pf.Shape)); err != nil {
+			return Piece{}, err
+		}
+		p = newPiece(pf.Name, pf.Color, kicks, pf.Shape...)
+	default:
+		return Piece{}, fmt.Errorf("piece needs a Shape or States")
+	}
+
+	for _, state := range p.states {
+		if len(state) == 0 {
+			return Piece{}, fmt.Errorf("piece has a state without any blocks")
+		}
+	}
+
+	if pf.Spins && p.size != 3 {
+		return Piece{}, fmt.Errorf("only pieces in a 3x3 box can spin, this one is %vx%v", p.size, p.size)
+	}
+
+	p.spawn = Vector{pf.Spawn[0], pf.Spawn[1]}
+	p.spins = pf.Spins
+	return p, nil
+}
+
+// Rows of a state have to make a size x size box
+func checkBox(rows []string, size int) error {
+	if len(rows) != size {
+		return fmt.Errorf("state is %v rows tall, expected %v", len(rows), size)
+	}
+	for _, row := range rows {
+		if len(row) != size {
+			return fmt.Errorf("state row %q isn't %v wide", row, size)
+		}
+	}
+	return nil
+}
+
+func parseKicks(data json.RawMessage) (kickTable, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		kicks, ok := namedKicks[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown kicks %q", name)
+		}
+		return kicks, nil
+	}
+
+	var table map[string][][2]int
+	if err := json.Unmarshal(data, &table); err != nil {
+		return nil, fmt.Errorf("kicks should be a name or a table: %w", err)
+	}
+
+	kicks := kickTable{}
+	for key, offsets := range table {
+		from, to, _ := strings.Cut(key, ">")
+		k := kick{from: rotationNames[from], to: rotationNames[to]}
+		if _, ok := rotationNames[from]; !ok {
+			return nil, fmt.Errorf("bad kick %q, expected something like \"0>R\"", key)
+		}
+		if _, ok := rotationNames[to]; !ok {
+			return nil, fmt.Errorf("bad kick %q, expected something like \"0>R\"", key)
+		}
+
+		for _, o := range offsets {
+			kicks[k] = append(kicks[k], Vector{o[0], o[1]})
+		}
+	}
+	return kicks, nil
+}
@@ -0,0 +1,128 @@
+package tetris
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPieceSetFiles(t *testing.T) {
+	paths, _ := filepath.Glob("../pieces/*.json")
+	if len(paths) == 0 {
+		t.Fatalf("No piece sets found")
+	}
+
+	for _, path := range paths {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			set, err := ParsePieceSet(data)
+			if err != nil {
+				t.Fatalf("Couldn't parse piece set: %v", err)
+			}
+
+			RegisterPieceSet(set)
+			rules := DefaultRules()
+			rules.PieceSet = set.Name
+			g := NewGame(20, 10, 1, rules)
+			if len(g.Pieces()) != len(set.Pieces) {
+				t.Errorf("Game has %v pieces, expected %v", len(g.Pieces()), len(set.Pieces))
+			}
+
+			// Shouldn't panic, even with pieces that don't look anything like tetrominoes
+			playRandomly(&g, 1, 3000)
+		})
+	}
+}
+
+func TestBadPieceSets(t *testing.T) {
+	cases := []struct {
+		name string
+		data string
+	}{
+		{"No name", `{"Pieces": [{"Name": "O", "Color": "red", "Shape": ["##", "##"]}]}`},
+		{"No pieces", `{"Name": "empty"}`},
+		{"No color", `{"Name": "x", "Pieces": [{"Name": "O", "Shape": ["##", "##"]}]}`},
+		{"Bad color", `{"Name": "x", "Pieces": [{"Name": "O", "Color": "#12", "Shape": ["##", "##"]}]}`},
+		{"Not square", `{"Name": "x", "Pieces": [{"Name": "O", "Color": "red", "Shape": ["##"]}]}`},
+		{"No blocks", `{"Name": "x", "Pieces": [{"Name": "O", "Color": "red", "Shape": ["..", ".."]}]}`},
+		{"Three states", `{"Name": "x", "Pieces": [{"Name": "O", "Color": "red", "States": [["#"], ["#"], ["#"]]}]}`},
+		{"Same name", `{"Name": "x", "Pieces": [{"Name": "O", "Color": "red", "Shape": ["#"]}, {"Name": "O", "Color": "red", "Shape": ["#"]}]}`},
+		{"Unknown kicks", `{"Name": "x", "Pieces": [{"Name": "O", "Color": "red", "Kicks": "arika", "Shape": ["#"]}]}`},
+		{"Bad kick", `{"Name": "x", "Pieces": [{"Name": "O", "Color": "red", "Kicks": {"0-R": [[0, 0]]}, "Shape": ["#"]}]}`},
+		{"Spins outside a 3x3 box", `{"Name": "x", "Pieces": [{"Name": "O", "Color": "red", "Spins": true, "Shape": ["##", "##"]}]}`},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if _, err := ParsePieceSet([]byte(c.data)); err == nil {
+				t.Errorf("Parsed a bad piece set without an error")
+			}
+		})
+	}
+}
+
+func TestPieceSetSpawnAndKicks(t *testing.T) {
+	set, err := ParsePieceSet([]byte(`{"Name": "test-kicks", "Pieces": [{
+		"Name": "Bar", "Color": "208", "Spawn": [2, 1],
+		"Kicks": {"0>R": [[5, 0], [0, 0]]},
+		"Shape": ["...", "###", "..."]
+	}]}`))
+	if err != nil {
+		t.Fatalf("Couldn't parse piece set: %v", err)
+	}
+	RegisterPieceSet(set)
+
+	rules := DefaultRules()
+	rules.PieceSet = set.Name
+	g := NewGame(20, 10, 0, rules)
+
+	// Normally a 3 wide box spawns at x 3 with its top block on row 0
+	if g.pos != (Vector{5, 0}) {
+		t.Errorf("Expected the piece to spawn at %v, it's at %v", Vector{5, 0}, g.pos)
+	}
+
+	// The first kick would go off the board, so the second is used
+	g.Act(ActionRotate)
+	if g.rot != RotationR || g.lastKick != 1 {
+		t.Errorf("Expected to rotate with kick 1, got rotation %v kick %v", g.rot, g.lastKick)
+	}
+
+	if _, err := NewGameFrom(20, 10, 0, Rules{PieceSet: "not registered"}, Setup{}); err == nil {
+		t.Errorf("Started a game with a piece set that isn't registered")
+	}
+}
+
+func TestPieceSetSpins(t *testing.T) {
+	set, err := ParsePieceSet([]byte(`{"Name": "test-spins", "Pieces": [
+		{"Name": "Spinner", "Color": "red", "Spins": true, "Shape": [".#.", "###", "..."]},
+		{"Name": "T", "Color": "purple", "Shape": [".#.", "###", "..."]}
+	]}`))
+	if err != nil {
+		t.Fatalf("Couldn't parse piece set: %v", err)
+	}
+
+	// Being named T doesn't make it spin, the set has to say so
+	if !set.Pieces[0].spins || set.Pieces[1].spins {
+		t.Errorf("Expected only Spinner to spin, got %v and %v", set.Pieces[0].spins, set.Pieces[1].spins)
+	}
+}
+
+func TestColorText(t *testing.T) {
+	for _, c := range []Color{ColorRed, ColorGray, ANSIColor(0), ANSIColor(208), RGBColor(255, 136, 0), RGBColor(0, 0, 0)} {
+		text, _ := c.MarshalText()
+		var parsed Color
+		if err := parsed.UnmarshalText(text); err != nil || parsed != c {
+			t.Errorf("%v came back as %v (%v)", c, parsed, err)
+		}
+	}
+
+	if r, g, b, ok := RGBColor(1, 2, 3).RGB(); !ok || r != 1 || g != 2 || b != 3 {
+		t.Errorf("RGB color lost its parts")
+	}
+	if _, ok := ColorRed.ANSI(); ok {
+		t.Errorf("Named colors aren't ANSI colors")
+	}
+}
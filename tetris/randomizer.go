@@ -0,0 +1,220 @@
+package tetris
+
+import "math/rand/v2"
+
+// Randomizer decides what order pieces are dealt in. Next returns an index
+// into the game's piece set, or -1 if there are no pieces left.
+type Randomizer interface {
+	Next() int
+}
+
+type RandomizerType int
+
+const (
+	Randomizer7Bag RandomizerType = iota
+	Randomizer14Bag
+	RandomizerHistory
+	RandomizerUniform
+)
+
+func (t RandomizerType) String() string {
+	switch t {
+	case Randomizer7Bag:
+		return "7-bag"
+	case Randomizer14Bag:
+		return "14-bag"
+	case RandomizerHistory:
+		return "history"
+	case RandomizerUniform:
+		return "uniform"
+	default:
+		return "invalid RandomizerType"
+	}
+}
+
+// Build a randomizer of type t over a set of n pieces. The same seed always deals the same sequence.
+func NewRandomizer(t RandomizerType, seed uint64, n int) Randomizer {
+	switch t {
+	case Randomizer14Bag:
+		return NewBagRandomizer(seed, n, 2)
+	case RandomizerHistory:
+		return NewHistoryRandomizer(seed, n)
+	case RandomizerUniform:
+		return NewUniformRandomizer(seed, n)
+	default:
+		return NewBagRandomizer(seed, n, 1)
+	}
+}
+
+// Seeded PCG source. The source is kept next to the Rand so its state can be copied around later
+type rng struct {
+	src *rand.PCG
+	r   *rand.Rand
+}
+
+func newRNG(seed uint64) rng {
+	src := rand.NewPCG(seed, seed)
+	return rng{src: src, r: rand.New(src)}
+}
+
+func (r rng) clone() rng {
+	src := *r.src
+	return rng{src: &src, r: rand.New(&src)}
+}
+
+// Deals every piece `copies` times in a shuffled bag before refilling it.
+// One copy is the usual 7-bag, two copies is a 14-bag
+type BagRandomizer struct {
+	rng    rng
+	n      int
+	copies int
+	bag    []int
+}
+
+func NewBagRandomizer(seed uint64, n, copies int) *BagRandomizer {
+	return &BagRandomizer{
+		rng:    newRNG(seed),
+		n:      n,
+		copies: copies,
+	}
+}
+
+func (b *BagRandomizer) Next() int {
+	if len(b.bag) == 0 {
+		for c := 0; c < b.copies; c++ {
+			for i := 0; i < b.n; i++ {
+				b.bag = append(b.bag, i)
+			}
+		}
+
+		b.rng.r.Shuffle(len(b.bag), func(i, j int) {
+			b.bag[i], b.bag[j] = b.bag[j], b.bag[i]
+		})
+	}
+
+	idx := b.bag[0]
+	b.bag = b.bag[1:]
+	return idx
+}
+
+const (
+	historyRolls = 6
+	historySize  = 4
+)
+
+// TGM style randomizer. Rerolls a few times if the piece was one of the last
+// few dealt, which makes droughts and repeats rare without being a strict bag
+type HistoryRandomizer struct {
+	rng     rng
+	n       int
+	history []int
+}
+
+// start is what to count as already dealt, oldest first. Without it nothing has been dealt yet
+func NewHistoryRandomizer(seed uint64, n int, start ...int) *HistoryRandomizer {
+	history := make([]int, historySize)
+	for i := range history {
+		history[i] = -1
+	}
+	copy(history[max(0, historySize-len(start)):], start[max(0, len(start)-historySize):])
+
+	return &HistoryRandomizer{
+		rng:     newRNG(seed),
+		n:       n,
+		history: history,
+	}
+}
+
+// The rules' randomizer over a set of n pieces. With the standard pieces the history randomizer
+// starts from the same history as TGM2, so the first piece is unlikely to be an S or Z
+func (r Rules) randomizer(seed uint64, n int) Randomizer {
+	if r.Randomizer == RandomizerHistory && (r.PieceSet == "" || r.PieceSet == StandardPieceSet) {
+		return NewHistoryRandomizer(seed, n, PieceZ, PieceS, PieceZ, PieceS)
+	}
+	return NewRandomizer(r.Randomizer, seed, n)
+}
+
+func (h *HistoryRandomizer) inHistory(idx int) bool {
+	for _, p := range h.history {
+		if p == idx {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *HistoryRandomizer) Next() int {
+	idx := h.rng.r.IntN(h.n)
+	for roll := 1; roll < historyRolls && h.inHistory(idx); roll++ {
+		idx = h.rng.r.IntN(h.n)
+	}
+
+	h.history = append(h.history[1:], idx)
+	return idx
+}
+
+// Every piece is equally likely every time
+type UniformRandomizer struct {
+	rng rng
+	n   int
+}
+
+func NewUniformRandomizer(seed uint64, n int) *UniformRandomizer {
+	return &UniformRandomizer{rng: newRNG(seed), n: n}
+}
+
+func (u *UniformRandomizer) Next() int {
+	return u.rng.r.IntN(u.n)
+}
+
+// Deals a fixed sequence of pieces first, then hands over to another randomizer.
+// Without one to hand over to, it runs out
+type SequenceRandomizer struct {
+	seq  []int
+	next int // Position in seq
+	then Randomizer
+}
+
+func NewSequenceRandomizer(seq []int, then Randomizer) *SequenceRandomizer {
+	return &SequenceRandomizer{seq: seq, then: then}
+}
+
+func (s *SequenceRandomizer) Next() int {
+	if s.next < len(s.seq) {
+		s.next++
+		return s.seq[s.next-1]
+	}
+	if s.then == nil {
+		return -1
+	}
+	return s.then.Next()
+}
+
+// Copy a randomizer's state, so the copy deals the same pieces without moving the original along.
+// Randomizers from outside this package can't be copied, so they're shared
+func cloneRandomizer(r Randomizer) Randomizer {
+	switch r := r.(type) {
+	case *BagRandomizer:
+		c := *r
+		c.rng = r.rng.clone()
+		c.bag = r.bag[:len(r.bag):len(r.bag)]
+		return &c
+	case *HistoryRandomizer:
+		c := *r
+		c.rng = r.rng.clone()
+		c.history = append([]int(nil), r.history...)
+		return &c
+	case *UniformRandomizer:
+		c := *r
+		c.rng = r.rng.clone()
+		return &c
+	case *SequenceRandomizer:
+		c := *r
+		if r.then != nil {
+			c.then = cloneRandomizer(r.then)
+		}
+		return &c
+	default:
+		return r
+	}
+}
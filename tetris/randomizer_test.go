@@ -0,0 +1,89 @@
+package tetris
+
+import (
+	"slices"
+	"testing"
+)
+
+func deal(r Randomizer, n int) []int {
+	seq := make([]int, n)
+	for i := range seq {
+		seq[i] = r.Next()
+	}
+	return seq
+}
+
+func TestRandomizerSeeds(t *testing.T) {
+	types := []RandomizerType{Randomizer7Bag, Randomizer14Bag, RandomizerHistory, RandomizerUniform}
+
+	for _, rt := range types {
+		t.Run(rt.String(), func(t *testing.T) {
+			a := deal(NewRandomizer(rt, 42, len(Pieces)), 100)
+			b := deal(NewRandomizer(rt, 42, len(Pieces)), 100)
+
+			for i := range a {
+				if a[i] != b[i] {
+					t.Fatalf("Same seed dealt different pieces at %v: %v != %v", i, a[i], b[i])
+				}
+				if a[i] < 0 || a[i] >= len(Pieces) {
+					t.Fatalf("Dealt an index outside the piece set: %v", a[i])
+				}
+			}
+		})
+	}
+}
+
+func TestBagRandomizer(t *testing.T) {
+	cases := []struct {
+		name   string
+		copies int
+	}{
+		{"7-bag", 1},
+		{"14-bag", 2},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			n := len(Pieces)
+			r := NewBagRandomizer(7, n, c.copies)
+
+			// Every bag should have each piece exactly `copies` times
+			for bag := 0; bag < 10; bag++ {
+				counts := make([]int, n)
+				for _, idx := range deal(r, n*c.copies) {
+					counts[idx]++
+				}
+
+				for idx, count := range counts {
+					if count != c.copies {
+						t.Errorf("Bag %v dealt piece %v %v times, expected %v", bag, idx, count, c.copies)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestHistoryRandomizerStart(t *testing.T) {
+	cases := []struct {
+		name    string
+		rules   Rules
+		history []int
+	}{
+		{"Standard pieces start like TGM", Rules{Randomizer: RandomizerHistory}, []int{PieceZ, PieceS, PieceZ, PieceS}},
+		{"Other sets start with nothing dealt", Rules{Randomizer: RandomizerHistory, PieceSet: "pentominoes"}, []int{-1, -1, -1, -1}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r := c.rules.randomizer(0, 12).(*HistoryRandomizer)
+			if !slices.Equal(r.history, c.history) {
+				t.Errorf("Expected history %v, got %v", c.history, r.history)
+			}
+		})
+	}
+
+	if r := NewHistoryRandomizer(0, 3, 0, 1, 2, 0, 1); !slices.Equal(r.history, []int{1, 2, 0, 1}) {
+		t.Errorf("Expected only the newest of a long start to be kept, got %v", r.history)
+	}
+}
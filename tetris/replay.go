@@ -0,0 +1,144 @@
+package tetris
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Bumped whenever a change to the engine or this format would make old replays play out differently
+const ReplayVersion = 2
+
+// Everything needed to play a game back exactly: how it was set up, and
+// every action on the frame it happened
+type Replay struct {
+	Version int
+	Height  int
+	Width   int
+	Seed    uint64
+	Rules   Rules
+	Setup   Setup
+	Inputs  []ReplayInput
+	Frames  int // How long the game ran for
+	Score   int
+	Lines   int
+}
+
+// An action, or garbage coming in if Garbage is set
+type ReplayInput struct {
+	Frame   int
+	Action  Action
+	Garbage *Garbage `json:",omitempty"`
+	Queued  bool     `json:",omitempty"` // Was the garbage queued, or added right away?
+}
+
+// A replay of the game so far
+func (g Game) Replay() Replay {
+	inputs := g.allInputs()
+
+	return Replay{
+		Version: ReplayVersion,
+		Height:  g.height,
+		Width:   g.width,
+		Seed:    g.seed,
+		Rules:   g.rules,
+		Setup:   g.setup,
+		Inputs:  inputs,
+		Frames:  g.frames,
+		Score:   g.score,
+		Lines:   g.lines,
+	}
+}
+
+// Inputs recorded before a game was cloned, shared by every clone made from that point
+type inputChunk struct {
+	prev   *inputChunk
+	inputs []ReplayInput
+	total  int // Inputs in this chunk and every one before it
+}
+
+func (g Game) inputCount() int {
+	if g.earlier == nil {
+		return len(g.inputs)
+	}
+	return g.earlier.total + len(g.inputs)
+}
+
+// Every input recorded so far, in a new slice
+func (g Game) allInputs() []ReplayInput {
+	inputs := make([]ReplayInput, g.inputCount())
+	end := copy(inputs[len(inputs)-len(g.inputs):], g.inputs)
+	end = len(inputs) - end
+	for c := g.earlier; c != nil; c = c.prev {
+		end -= copy(inputs[end-len(c.inputs):end], c.inputs)
+	}
+	return inputs
+}
+
+func (g *Game) record(a Action) {
+	g.inputs = append(g.inputs, ReplayInput{Frame: g.frames, Action: a})
+}
+
+func (g *Game) recordGarbage(gb Garbage, queued bool) {
+	g.inputs = append(g.inputs, ReplayInput{Frame: g.frames, Garbage: &gb, Queued: queued})
+}
+
+// Feed a recorded input back into the game
+func (g *Game) replay(in ReplayInput) {
+	switch {
+	case in.Garbage == nil:
+		g.Act(in.Action)
+	case in.Queued:
+		g.QueueGarbage(in.Garbage.Lines, in.Garbage.Hole)
+	default:
+		g.AddGarbage(in.Garbage.Lines, in.Garbage.Hole)
+	}
+}
+
+// A fresh game set up the same way the replay's game was. Only errors for replays that didn't come from ParseReplay or Game.Replay
+func (r Replay) NewGame() (Game, error) {
+	return NewGameFrom(r.Height, r.Width, r.Seed, r.Rules, r.Setup)
+}
+
+// Step g forward by frames, feeding it the replay's inputs as their frames come up.
+// g should come from NewGame, and only ever be advanced by the replay
+func (r Replay) Advance(g *Game, frames int) []Event {
+	var events []Event
+	// Every input the replay has fed g so far got recorded by g again
+	next := g.inputCount()
+
+	for i := 0; i < frames && !g.GameOver && g.frames <= r.Frames; i++ {
+		for ; next < len(r.Inputs) && r.Inputs[next].Frame == g.frames; next++ {
+			g.replay(r.Inputs[next])
+		}
+
+		if g.frames == r.Frames {
+			// The game ended here, only the inputs are left
+			events = append(events, g.Events()...)
+			break
+		}
+		events = append(events, g.Step(1, nil)...)
+	}
+
+	return events
+}
+
+func (r Replay) Marshal() ([]byte, error) {
+	return json.Marshal(r)
+}
+
+func ParseReplay(data []byte) (Replay, error) {
+	var r Replay
+	if err := json.Unmarshal(data, &r); err != nil {
+		return r, err
+	}
+
+	if r.Version != ReplayVersion {
+		return r, fmt.Errorf("replay is version %v, this server plays version %v", r.Version, ReplayVersion)
+	}
+
+	// Make sure NewGame will work
+	if _, err := r.NewGame(); err != nil {
+		return r, fmt.Errorf("replay can't be set up: %w", err)
+	}
+	return r, nil
+}
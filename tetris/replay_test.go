@@ -0,0 +1,68 @@
+package tetris
+
+import (
+	"math/rand/v2"
+	"reflect"
+	"testing"
+)
+
+// Mash random buttons on random frames
+func playRandomly(g *Game, seed uint64, frames int) {
+	r := rand.New(rand.NewPCG(seed, seed))
+	for i := 0; i < frames && !g.GameOver; {
+		n := r.IntN(20)
+		g.Step(n, nil)
+		i += n
+
+		var inputs []Action
+		for a := r.IntN(3); a > 0; a-- {
+			inputs = append(inputs, Action(r.IntN(int(ActionHold)+1)))
+		}
+		g.Step(0, inputs)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	for _, seed := range []uint64{1, 2, 3} {
+		g := NewGame(20, 10, seed, DefaultRules())
+		playRandomly(&g, seed, 5000)
+
+		data, err := g.Replay().Marshal()
+		if err != nil {
+			t.Fatalf("Couldn't marshal replay: %v", err)
+		}
+		r, err := ParseReplay(data)
+		if err != nil {
+			t.Fatalf("Couldn't parse replay: %v", err)
+		}
+
+		// Play it back in uneven chunks, like a player at a weird speed would
+		played, err := r.NewGame()
+		if err != nil {
+			t.Fatalf("Couldn't set up replay: %v", err)
+		}
+		for chunk := 1; played.frames < r.Frames && !played.GameOver; chunk = chunk%7 + 1 {
+			r.Advance(&played, chunk)
+		}
+		r.Advance(&played, 1)
+
+		if played.Score() != g.Score() || played.Frames() != g.Frames() || played.GameOver != g.GameOver {
+			t.Errorf("Seed %v: replay ended with score %v at frame %v (game over: %v), expected %v at %v (%v)",
+				seed, played.Score(), played.Frames(), played.GameOver, g.Score(), g.Frames(), g.GameOver)
+		}
+		if !reflect.DeepEqual(played.Board(), g.Board()) {
+			t.Errorf("Seed %v: replay ended on a different board", seed)
+		}
+	}
+}
+
+func TestReplayVersion(t *testing.T) {
+	g := NewGame(20, 10, 0, DefaultRules())
+	r := g.Replay()
+	r.Version = ReplayVersion + 1
+
+	data, _ := r.Marshal()
+	if _, err := ParseReplay(data); err == nil {
+		t.Errorf("Parsed a replay from a different version without an error")
+	}
+}
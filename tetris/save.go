@@ -0,0 +1,304 @@
+package tetris
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+)
+
+// Bumped whenever the saved format changes in a way old saves can't be loaded into
+const SaveVersion = 1
+
+// Everything in a game that has to survive a save, in a form encoding/json can handle.
+// Pieces are saved by name and looked up in the piece set when loading
+type savedGame struct {
+	Version    int
+	Height     int
+	Width      int
+	Seed       uint64
+	Rules      Rules
+	Setup      Setup
+	Board      [][]int
+	Piece      string
+	Rot        Rotation
+	Pos        [2]int
+	Queue      []string
+	Hold       string // Empty for nothing held
+	HoldUsed   bool
+	Garbage    []Garbage
+	Lock       savedLock
+	LastKick   int
+	Combo      int
+	B2B        bool
+	Level      int
+	Lines      int
+	Score      int
+	Phase      phase
+	Delay      int
+	Gravity    float64
+	Frames     int
+	Inputs     []ReplayInput
+	Randomizer json.RawMessage
+	Outcome    Outcome
+	Progress   savedProgress
+	GameOver   bool
+}
+
+type savedLock struct {
+	Frames, Resets, Lowest int
+}
+
+type savedProgress struct {
+	Placed             int
+	Spin, PerfectClear bool
+}
+
+// Save the whole game state, randomizer included, so it can pick up exactly where it left off.
+// The scorer and any uncollected events aren't saved
+func (g Game) MarshalJSON() ([]byte, error) {
+	r, err := json.Marshal(g.rand)
+	if err != nil {
+		return nil, err
+	}
+
+	s := savedGame{
+		Version:    SaveVersion,
+		Height:     g.height,
+		Width:      g.width,
+		Seed:       g.seed,
+		Rules:      g.rules,
+		Setup:      g.setup,
+		Board:      g.board,
+		Piece:      g.piece.name,
+		Rot:        g.rot,
+		Pos:        [2]int{g.pos.x, g.pos.y},
+		HoldUsed:   g.holdUsed,
+		Garbage:    g.garbage,
+		Lock:       savedLock{g.lock.frames, g.lock.resets, g.lock.lowest},
+		LastKick:   g.lastKick,
+		Combo:      g.combo,
+		B2B:        g.b2b,
+		Level:      g.level,
+		Lines:      g.lines,
+		Score:      g.score,
+		Phase:      g.phase,
+		Delay:      g.delay,
+		Gravity:    g.gravity,
+		Frames:     g.frames,
+		Inputs:     g.allInputs(),
+		Randomizer: r,
+		Outcome:    g.outcome,
+		Progress:   savedProgress{g.progress.placed, g.progress.spin, g.progress.perfectClear},
+		GameOver:   g.GameOver,
+	}
+
+	for _, p := range g.queue {
+		s.Queue = append(s.Queue, p.name)
+	}
+	if g.hold != nil {
+		s.Hold = g.hold.name
+	}
+
+	return json.Marshal(s)
+}
+
+// Load a game saved with MarshalJSON. Games come back with GuidelineScorer
+func (g *Game) UnmarshalJSON(data []byte) error {
+	var s savedGame
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+
+	if s.Version != SaveVersion {
+		return fmt.Errorf("save is version %v, this server loads version %v", s.Version, SaveVersion)
+	}
+
+	if err := checkBoard(s.Board, s.Height, s.Width); err != nil {
+		return fmt.Errorf("saved %w", err)
+	}
+
+	pieces, err := s.Rules.pieces()
+	if err != nil {
+		return err
+	}
+	r, err := newRandomizer(s.Rules, s.Seed, s.Setup, pieces)
+	if err != nil {
+		return err
+	}
+
+	loaded := Game{
+		board:    NewBoard(s.Height, s.Width),
+		pieces:   pieces,
+		rand:     r,
+		seed:     s.Seed,
+		rules:    s.Rules,
+		setup:    s.Setup,
+		rot:      s.Rot,
+		pos:      Vector{s.Pos[0], s.Pos[1]},
+		holdUsed: s.HoldUsed,
+		garbage:  s.Garbage,
+		lock:     lockState{frames: s.Lock.Frames, resets: s.Lock.Resets, lowest: s.Lock.Lowest},
+		lastKick: s.LastKick,
+		scorer:   GuidelineScorer{},
+		combo:    s.Combo,
+		b2b:      s.B2B,
+		level:    s.Level,
+		lines:    s.Lines,
+		phase:    s.Phase,
+		delay:    s.Delay,
+		gravity:  s.Gravity,
+		frames:   s.Frames,
+		inputs:   s.Inputs,
+		outcome:  s.Outcome,
+		progress: goalProgress{placed: s.Progress.Placed, spin: s.Progress.Spin, perfectClear: s.Progress.PerfectClear},
+		height:   s.Height,
+		width:    s.Width,
+		score:    s.Score,
+		GameOver: s.GameOver,
+	}
+
+	for y := range s.Board {
+		copy(loaded.board[y], s.Board[y])
+	}
+
+	if err := json.Unmarshal(s.Randomizer, loaded.rand); err != nil {
+		return fmt.Errorf("couldn't restore randomizer: %w", err)
+	}
+
+	if loaded.piece, err = loaded.pieceNamed(s.Piece); err != nil {
+		return err
+	}
+	for _, name := range s.Queue {
+		p, err := loaded.pieceNamed(name)
+		if err != nil {
+			return err
+		}
+		loaded.queue = append(loaded.queue, p)
+	}
+	if s.Hold != "" {
+		p, err := loaded.pieceNamed(s.Hold)
+		if err != nil {
+			return err
+		}
+		loaded.hold = &p
+	}
+
+	*g = loaded
+	return nil
+}
+
+func (g Game) pieceNamed(name string) (Piece, error) {
+	if i := pieceIndex(g.pieces, name); i >= 0 {
+		return g.pieces[i], nil
+	}
+	return Piece{}, fmt.Errorf("no piece named %q in the piece set", name)
+}
+
+/*** RANDOMIZER STATE ***/
+
+func (r rng) MarshalJSON() ([]byte, error) {
+	state, err := r.src.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(state)
+}
+
+func (r *rng) UnmarshalJSON(data []byte) error {
+	var state []byte
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+
+	src := new(rand.PCG)
+	if err := src.UnmarshalBinary(state); err != nil {
+		return err
+	}
+
+	r.src = src
+	r.r = rand.New(src)
+	return nil
+}
+
+type bagState struct {
+	RNG rng
+	Bag []int
+}
+
+func (b *BagRandomizer) MarshalJSON() ([]byte, error) {
+	return json.Marshal(bagState{RNG: b.rng, Bag: b.bag})
+}
+
+func (b *BagRandomizer) UnmarshalJSON(data []byte) error {
+	var s bagState
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	b.rng, b.bag = s.RNG, s.Bag
+	return nil
+}
+
+type historyState struct {
+	RNG     rng
+	History []int
+}
+
+func (h *HistoryRandomizer) MarshalJSON() ([]byte, error) {
+	return json.Marshal(historyState{RNG: h.rng, History: h.history})
+}
+
+func (h *HistoryRandomizer) UnmarshalJSON(data []byte) error {
+	var s historyState
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	h.rng, h.history = s.RNG, s.History
+	return nil
+}
+
+type uniformState struct {
+	RNG rng
+}
+
+func (u *UniformRandomizer) MarshalJSON() ([]byte, error) {
+	return json.Marshal(uniformState{RNG: u.rng})
+}
+
+func (u *UniformRandomizer) UnmarshalJSON(data []byte) error {
+	var s uniformState
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	u.rng = s.RNG
+	return nil
+}
+
+type sequenceState struct {
+	Next int
+	Then json.RawMessage
+}
+
+func (s *SequenceRandomizer) MarshalJSON() ([]byte, error) {
+	if s.then == nil {
+		return json.Marshal(sequenceState{Next: s.next})
+	}
+
+	then, err := json.Marshal(s.then)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(sequenceState{Next: s.next, Then: then})
+}
+
+// The sequence itself comes from the game's Setup, only how far into it we are is saved
+func (s *SequenceRandomizer) UnmarshalJSON(data []byte) error {
+	var st sequenceState
+	if err := json.Unmarshal(data, &st); err != nil {
+		return err
+	}
+	s.next = st.Next
+	if s.then == nil {
+		return nil
+	}
+	return json.Unmarshal(st.Then, s.then)
+}
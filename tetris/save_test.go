@@ -0,0 +1,83 @@
+package tetris
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestSaveAndLoad(t *testing.T) {
+	types := []RandomizerType{Randomizer7Bag, Randomizer14Bag, RandomizerHistory, RandomizerUniform}
+
+	for _, rt := range types {
+		t.Run(rt.String(), func(t *testing.T) {
+			rules := DefaultRules()
+			rules.Randomizer = rt
+			g := NewGame(20, 10, 5, rules)
+			checkSaveAndLoad(t, g)
+		})
+	}
+
+	t.Run("setup", func(t *testing.T) {
+		setup, _ := DecodeFumen("v115@HhglIeglIehlAezhMeAgH", 20, 10)
+		setup.Sequence = []string{"T", "T", "T", "T", "T", "T", "T", "T", "T", "T"}
+		g, err := NewGameFrom(20, 10, 5, DefaultRules(), setup)
+		if err != nil {
+			t.Fatalf("Couldn't set up game: %v", err)
+		}
+		checkSaveAndLoad(t, g)
+	})
+}
+
+// Play g for a bit, save and load it, then check both copies carry on exactly the same
+func checkSaveAndLoad(t *testing.T, g Game) {
+	playRandomly(&g, 5, 600)
+	g.Act(ActionHold)
+
+	data, err := json.Marshal(g)
+	if err != nil {
+		t.Fatalf("Couldn't save game: %v", err)
+	}
+	var loaded Game
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		t.Fatalf("Couldn't load game: %v", err)
+	}
+
+	playRandomly(&g, 6, 3000)
+	playRandomly(&loaded, 6, 3000)
+
+	if !reflect.DeepEqual(loaded.Board(), g.Board()) || loaded.Score() != g.Score() || loaded.Frames() != g.Frames() {
+		t.Errorf("Loaded game played out differently from the original")
+	}
+	if !reflect.DeepEqual(loaded.Replay(), g.Replay()) {
+		t.Errorf("Loaded game's replay doesn't match the original's")
+	}
+}
+
+func TestLoadBadSaves(t *testing.T) {
+	g := NewGame(20, 10, 0, DefaultRules())
+	data, _ := json.Marshal(g)
+
+	cases := []struct {
+		name   string
+		change func(s map[string]any)
+	}{
+		{"Wrong version", func(s map[string]any) { s["Version"] = SaveVersion + 1 }},
+		{"Unknown piece", func(s map[string]any) { s["Piece"] = "W" }},
+		{"Short board", func(s map[string]any) { s["Height"] = 21 }},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var s map[string]any
+			json.Unmarshal(data, &s)
+			c.change(s)
+			bad, _ := json.Marshal(s)
+
+			var loaded Game
+			if err := json.Unmarshal(bad, &loaded); err == nil {
+				t.Errorf("Loaded a bad save without an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package tetris
+
+import "fmt"
+
+type SpinType int
+
+const (
+	SpinNone SpinType = iota
+	SpinMini
+	SpinFull
+)
+
+func (s SpinType) String() string {
+	switch s {
+	case SpinNone:
+		return "none"
+	case SpinMini:
+		return "mini T-spin"
+	case SpinFull:
+		return "T-spin"
+	default:
+		return "invalid SpinType"
+	}
+}
+
+// Spins are written by name in files, like "T-spin"
+func (s SpinType) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s *SpinType) UnmarshalText(text []byte) error {
+	for t := SpinNone; t <= SpinFull; t++ {
+		if t.String() == string(text) {
+			*s = t
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown spin %q", text)
+}
+
+// What happened when a piece locked
+type Clear struct {
+	Rows         []int // Rows that were cleared, top to bottom, numbered as they were before clearing
+	Spin         SpinType
+	PerfectClear bool
+	// Consecutive clears before this one, -1 if this piece didn't clear anything
+	Combo int
+	// Was this a difficult clear following another difficult clear?
+	BackToBack bool
+	// Level the piece locked on
+	Level int
+}
+
+func (c Clear) Lines() int {
+	return len(c.Rows)
+}
+
+// Tetrises and spins that clear lines keep a back to back going
+func (c Clear) Difficult() bool {
+	return c.Lines() >= 4 || (c.Spin != SpinNone && c.Lines() > 0)
+}
+
+// Turns what the player did into points
+type Scorer interface {
+	// Points for a piece locking
+	LockScore(c Clear) int
+	// Points for soft or hard dropping some number of cells
+	DropScore(cells int, hard bool) int
+}
+
+// Scoring from the tetris guideline. Everything but drops is multiplied by the level
+type GuidelineScorer struct{}
+
+var (
+	// Indexed by lines cleared
+	guidelineLines = []int{0, 100, 300, 500, 800}
+	guidelineMini  = []int{100, 200, 400}
+	guidelineSpin  = []int{400, 800, 1200, 1600}
+	guidelinePC    = []int{0, 800, 1200, 1800, 2000}
+)
+
+const (
+	guidelineCombo     = 50
+	guidelineB2BTetris = 3200 // Replaces the normal perfect clear bonus
+)
+
+// Look up lines in a table, using the last entry for anything past the end
+func tableScore(table []int, lines int) int {
+	return table[min(lines, len(table)-1)]
+}
+
+func (GuidelineScorer) LockScore(c Clear) int {
+	var points int
+	switch c.Spin {
+	case SpinMini:
+		points = tableScore(guidelineMini, c.Lines())
+	case SpinFull:
+		points = tableScore(guidelineSpin, c.Lines())
+	default:
+		points = tableScore(guidelineLines, c.Lines())
+	}
+
+	if c.BackToBack {
+		points += points / 2
+	}
+
+	if c.Combo > 0 {
+		points += guidelineCombo * c.Combo
+	}
+
+	if c.PerfectClear {
+		if c.BackToBack && c.Lines() >= 4 {
+			points += guidelineB2BTetris
+		} else {
+			points += tableScore(guidelinePC, c.Lines())
+		}
+	}
+
+	return points * max(c.Level, 1)
+}
+
+func (GuidelineScorer) DropScore(cells int, hard bool) int {
+	if hard {
+		return cells * 2
+	}
+	return cells
+}
+
+// Corners of the T's 3x3 box, and the two on the side it's pointing at for each orientation
+var (
+	tCorners      = []Vector{{0, 0}, {2, 0}, {0, 2}, {2, 2}}
+	tFrontCorners = [4][2]Vector{
+		Rotation0: {{0, 0}, {2, 0}},
+		RotationR: {{2, 0}, {2, 2}},
+		Rotation2: {{0, 2}, {2, 2}},
+		RotationL: {{0, 0}, {0, 2}},
+	}
+)
+
+// 3 corner rule, for pieces that can spin (just the T in the standard set). A T
+// that got where it is by rotating and has 3 of the corners around its center
+// filled is a T-spin. It's a mini unless both corners it's pointing at are
+// filled, or it took the last kick to get there (the TST kick)
+func (g Game) spin() SpinType {
+	if !g.piece.spins || g.lastKick < 0 {
+		return SpinNone
+	}
+
+	filled := func(v Vector) bool {
+		c, ok := g.colorAt(g.pos.add(v))
+		return !ok || c > 0
+	}
+
+	corners := 0
+	for _, v := range tCorners {
+		if filled(v) {
+			corners++
+		}
+	}
+	if corners < 3 {
+		return SpinNone
+	}
+
+	front := tFrontCorners[g.rot]
+	lastKick := len(g.piece.kicksFor(g.rot.ccw(), g.rot)) - 1
+	if (filled(front[0]) && filled(front[1])) || g.lastKick == lastKick {
+		return SpinFull
+	}
+	return SpinMini
+}
@@ -0,0 +1,194 @@
+package tetris
+
+import "testing"
+
+func rows(n int) []int {
+	r := make([]int, n)
+	for i := range r {
+		r[i] = 19 - i
+	}
+	return r
+}
+
+func TestGuidelineLockScore(t *testing.T) {
+	cases := []struct {
+		name   string
+		clear  Clear
+		points int
+	}{
+		{"Nothing", Clear{Combo: -1}, 0},
+		{"Single", Clear{Rows: rows(1)}, 100},
+		{"Double", Clear{Rows: rows(2)}, 300},
+		{"Triple", Clear{Rows: rows(3)}, 500},
+		{"Tetris", Clear{Rows: rows(4)}, 800},
+		{"Mini T-spin", Clear{Spin: SpinMini, Combo: -1}, 100},
+		{"Mini T-spin single", Clear{Rows: rows(1), Spin: SpinMini}, 200},
+		{"T-spin", Clear{Spin: SpinFull, Combo: -1}, 400},
+		{"T-spin single", Clear{Rows: rows(1), Spin: SpinFull}, 800},
+		{"T-spin double", Clear{Rows: rows(2), Spin: SpinFull}, 1200},
+		{"T-spin triple", Clear{Rows: rows(3), Spin: SpinFull}, 1600},
+		{"Back to back tetris", Clear{Rows: rows(4), BackToBack: true}, 1200},
+		{"Back to back T-spin double", Clear{Rows: rows(2), Spin: SpinFull, BackToBack: true}, 1800},
+		{"Combo", Clear{Rows: rows(1), Combo: 3}, 250},
+		{"Perfect clear single", Clear{Rows: rows(1), PerfectClear: true}, 900},
+		{"Perfect clear tetris", Clear{Rows: rows(4), PerfectClear: true}, 2800},
+		{"Back to back perfect clear tetris", Clear{Rows: rows(4), PerfectClear: true, BackToBack: true}, 4400},
+		{"Level 3 tetris", Clear{Rows: rows(4), Level: 3}, 2400},
+		{"Level 2 combo", Clear{Rows: rows(1), Combo: 1, Level: 2}, 300},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if points := (GuidelineScorer{}).LockScore(c.clear); points != c.points {
+				t.Errorf("Expected %v points, got %v", c.points, points)
+			}
+		})
+	}
+}
+
+func TestGuidelineDropScore(t *testing.T) {
+	s := GuidelineScorer{}
+	if p := s.DropScore(5, false); p != 5 {
+		t.Errorf("Soft drop should be 1 point a cell, got %v for 5", p)
+	}
+	if p := s.DropScore(5, true); p != 10 {
+		t.Errorf("Hard drop should be 2 points a cell, got %v for 5", p)
+	}
+}
+
+// Fill the cells in a game's board, (x, y) pairs
+func fill(g *Game, cells ...Vector) {
+	for _, c := range cells {
+		g.board[c.y][c.x] = 1
+	}
+}
+
+// Fill row y apart from the listed columns
+func fillRow(g *Game, y int, holes ...int) {
+	for x := range g.board[y] {
+		g.board[y][x] = 1
+	}
+	for _, x := range holes {
+		g.board[y][x] = 0
+	}
+}
+
+func TestTSpins(t *testing.T) {
+	cases := []struct {
+		name  string
+		setup func(g *Game)
+		rot   Rotation // Orientation the T starts in before one clockwise turn
+		lines int
+		spin  SpinType
+	}{
+		{
+			name: "T-spin double",
+			setup: func(g *Game) {
+				fillRow(g, 18, 3, 4, 5)
+				fillRow(g, 19, 4)
+				fill(g, Vector{3, 17}) // Overhang
+			},
+			rot: RotationR, lines: 2, spin: SpinFull,
+		},
+		{
+			name: "Mini T-spin",
+			setup: func(g *Game) {
+				fill(g, Vector{3, 17}, Vector{3, 19}, Vector{5, 19})
+			},
+			rot: Rotation0, lines: 0, spin: SpinMini,
+		},
+		{
+			name: "Not enough corners",
+			setup: func(g *Game) {
+				fill(g, Vector{3, 19}, Vector{5, 19})
+			},
+			rot: Rotation0, lines: 0, spin: SpinNone,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			g := NewGame(20, 10, 0, DefaultRules())
+			c.setup(&g)
+			placePiece(&g, PieceT, c.rot, Vector{3, 17})
+
+			g.Act(ActionRotate)
+			clear := g.lockPiece()
+
+			if clear.Spin != c.spin {
+				t.Errorf("Expected %v, got %v", c.spin, clear.Spin)
+			}
+			if clear.Lines() != c.lines {
+				t.Errorf("Expected %v lines, got %v", c.lines, clear.Lines())
+			}
+		})
+	}
+}
+
+func TestSpinningPieces(t *testing.T) {
+	// T shaped, but only as spin-eligible as the set says
+	tShaped := func(spins bool) Piece {
+		p := Pieces[PieceT]
+		p.name, p.spins = "Not T", spins
+		return p
+	}
+
+	cases := []struct {
+		name  string
+		piece Piece
+		spin  SpinType
+	}{
+		{"Standard T", Pieces[PieceT], SpinMini},
+		{"Set says it spins", tShaped(true), SpinMini},
+		{"Set doesn't say it spins", tShaped(false), SpinNone},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			g := NewGame(20, 10, 0, DefaultRules())
+			fill(&g, Vector{3, 17}, Vector{3, 19}, Vector{5, 19})
+			g.piece, g.rot, g.pos = c.piece, Rotation0, Vector{3, 17}
+
+			g.Act(ActionRotate)
+			if spin := g.lockPiece().Spin; spin != c.spin {
+				t.Errorf("Expected %v, got %v", c.spin, spin)
+			}
+		})
+	}
+}
+
+func TestMoveCancelsSpin(t *testing.T) {
+	g := NewGame(20, 10, 0, DefaultRules())
+	fill(&g, Vector{3, 17}, Vector{3, 19}, Vector{5, 19})
+	placePiece(&g, PieceT, Rotation0, Vector{2, 17})
+
+	g.Act(ActionRight)
+	if g.spin() != SpinNone {
+		t.Errorf("Moving into a spot shouldn't count as a spin")
+	}
+}
+
+func TestCombosAndBackToBack(t *testing.T) {
+	g := NewGame(20, 10, 0, DefaultRules())
+
+	clears := []struct {
+		clear      Clear
+		combo      int
+		backToBack bool
+	}{
+		{Clear{Rows: rows(4)}, 0, false},
+		{Clear{Rows: rows(4)}, 1, true},
+		{Clear{Rows: rows(1)}, 2, false},
+		{Clear{Rows: rows(2), Spin: SpinFull}, 3, false}, // The single broke the b2b
+		{Clear{}, -1, false},
+		{Clear{Rows: rows(1), Spin: SpinMini}, 0, true},
+	}
+
+	for i, c := range clears {
+		clear := c.clear
+		g.scoreClear(&clear)
+		if clear.Combo != c.combo || clear.BackToBack != c.backToBack {
+			t.Errorf("Clear %v: expected combo %v and b2b %v, got %v and %v", i, c.combo, c.backToBack, clear.Combo, clear.BackToBack)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package tetris
+
+import "fmt"
+
+// A position to start a game from instead of an empty board, like a board loaded from a fumen.
+// The zero Setup is a normal game
+type Setup struct {
+	Board    [][]int  // Starting blocks, the same size as the game. nil for an empty board
+	Sequence []string // Names of the first pieces to deal, before the randomizer takes over
+	Hold     string   // Piece that starts in the hold slot, empty for nothing
+	Only     bool     // Deal nothing after Sequence, for puzzles
+}
+
+// Start a game from a setup. Errors if the setup doesn't fit the board or uses pieces that aren't in the rules' piece set
+func NewGameFrom(height, width int, seed uint64, rules Rules, setup Setup) (Game, error) {
+	pieces, err := rules.pieces()
+	if err != nil {
+		return Game{}, err
+	}
+	r, err := newRandomizer(rules, seed, setup, pieces)
+	if err != nil {
+		return Game{}, err
+	}
+
+	g := blankGame(height, width, pieces, r, rules)
+	g.seed = seed
+	g.setup = setup
+
+	if setup.Board != nil {
+		if err := checkBoard(setup.Board, height, width); err != nil {
+			return Game{}, err
+		}
+		for y := range setup.Board {
+			copy(g.board[y], setup.Board[y])
+		}
+	}
+
+	if setup.Hold != "" {
+		p, err := g.pieceNamed(setup.Hold)
+		if err != nil {
+			return Game{}, err
+		}
+		g.hold = &p
+	}
+
+	if !g.nextPieceIfPossible() {
+		return Game{}, fmt.Errorf("no room for the first piece")
+	}
+	return g, nil
+}
+
+// The setup the game started from
+func (g Game) Setup() Setup {
+	return g.setup
+}
+
+// The randomizer from rules, dealing the setup's sequence first if it has one
+func newRandomizer(rules Rules, seed uint64, setup Setup, pieces []Piece) (Randomizer, error) {
+	r := rules.randomizer(seed, len(pieces))
+	if len(setup.Sequence) == 0 {
+		if setup.Only {
+			return nil, fmt.Errorf("setup only deals its sequence, but doesn't have one")
+		}
+		return r, nil
+	}
+
+	seq := make([]int, len(setup.Sequence))
+	for i, name := range setup.Sequence {
+		seq[i] = pieceIndex(pieces, name)
+		if seq[i] < 0 {
+			return nil, fmt.Errorf("no piece named %q in the piece set", name)
+		}
+	}
+	if setup.Only {
+		r = nil
+	}
+	return NewSequenceRandomizer(seq, r), nil
+}
+
+func pieceIndex(pieces []Piece, name string) int {
+	for i, p := range pieces {
+		if p.name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func checkBoard(board [][]int, height, width int) error {
+	if len(board) != height {
+		return fmt.Errorf("board has %v rows, expected %v", len(board), height)
+	}
+	for _, row := range board {
+		if len(row) != width {
+			return fmt.Errorf("board has a row %v wide, expected %v", len(row), width)
+		}
+	}
+	return nil
+}
+
+// Build a board from rows of text, top first, sitting on the bottom of a height x width board.
+// '.' or ' ' is empty, a piece's name is a block that piece's color, and anything else is gray
+func ParseBoard(height, width int, rows ...string) ([][]int, error) {
+	if len(rows) > height {
+		return nil, fmt.Errorf("%v rows don't fit on a board %v high", len(rows), height)
+	}
+
+	board := NewBoard(height, width)
+	for i, row := range rows {
+		y := height - len(rows) + i
+		if len(row) > width {
+			return nil, fmt.Errorf("row %q is wider than the board", row)
+		}
+
+		for x, c := range row {
+			switch i := pieceIndex(Pieces, string(c)); {
+			case c == '.' || c == ' ':
+			case i >= 0:
+				board[y][x] = Pieces[i].color
+			default:
+				board[y][x] = int(ColorGray)
+			}
+		}
+	}
+	return board, nil
+}
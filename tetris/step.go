@@ -0,0 +1,99 @@
+package tetris
+
+// What the game is doing between frames
+type phase int
+
+const (
+	phaseFalling phase = iota // There's an active piece
+	phaseEntry                // Waiting out ARE or the line clear delay before the next piece comes in
+	phaseOver                 // Topped out, so there's no piece, not even one that got pushed off the top
+)
+
+// Advance the game by some number of frames, applying inputs before the first one.
+// All of the game's timing lives here (gravity, lock delay, ARE, line clear delay),
+// so the same inputs on the same frames always play out the same way.
+// Returns everything that happened.
+func (g *Game) Step(frames int, inputs []Action) []Event {
+	for _, a := range inputs {
+		g.Act(a)
+	}
+
+	for i := 0; i < frames && !g.GameOver; i++ {
+		g.frame()
+	}
+
+	return g.Events()
+}
+
+// Frames the game has been running for
+func (g Game) Frames() int {
+	return g.frames
+}
+
+func (g *Game) frame() {
+	g.frames++
+
+	switch g.phase {
+	case phaseEntry:
+		g.delay--
+		if g.delay <= 0 {
+			g.spawnNext()
+		}
+	case phaseFalling:
+		g.applyGravity()
+
+		if g.grounded() {
+			g.lock.frames++
+			if g.lock.frames >= g.rules.LockDelay {
+				g.lockPiece()
+			}
+		}
+	}
+
+	if limit := g.rules.Goal.Frames; limit > 0 && g.frames >= limit && !g.GameOver {
+		g.timeUp()
+	}
+}
+
+// Build up gravity and drop the piece a row for every whole cell of it
+func (g *Game) applyGravity() {
+	if g.Gravity() >= Gravity20G {
+		g.gravity = float64(g.height)
+	} else {
+		g.gravity += g.Gravity()
+	}
+
+	for ; g.gravity >= 1; g.gravity-- {
+		if !g.moveIfPossible(Vector{0, 1}) {
+			// Gravity doesn't build up while the piece is sitting on something
+			g.gravity = 0
+			return
+		}
+		g.stepped()
+	}
+}
+
+// Wait out ARE, plus the line clear delay if lines were cleared, before spawning the next piece
+func (g *Game) startEntry(cleared bool) {
+	delay := g.rules.ARE
+	if cleared {
+		delay += g.rules.LineClearDelay
+	}
+
+	if delay <= 0 {
+		g.spawnNext()
+		return
+	}
+
+	g.phase = phaseEntry
+	g.delay = delay
+}
+
+func (g *Game) spawnNext() {
+	if p, ok := g.takeNext(); !ok {
+		// Only puzzles run out, and not finishing the puzzle in time is losing it
+		g.finish(OutcomeLost)
+	} else {
+		g.spawnOrTopOut(p)
+	}
+}